@@ -23,6 +23,7 @@ import (
 	disgo "github.com/sabafly/sabafly-disgo"
 	"github.com/sabafly/sabafly-disgo/bot"
 	"github.com/sabafly/sabafly-disgo/cache"
+	"github.com/sabafly/sabafly-disgo/discord"
 	"github.com/sabafly/sabafly-disgo/gateway"
 	"github.com/sabafly/sabafly-disgo/handlers"
 	"github.com/sabafly/sabafly-disgo/oauth2"
@@ -30,13 +31,37 @@ import (
 )
 
 func New[T any](logger log.Logger, version string, config Config) *Bot[T] {
-	return &Bot[T]{
+	h := handler.New(logger)
+	h.ErrorMessage = handlerErrorMessage
+	h.ErrorEmbeds = handlerErrorEmbeds
+	b := &Bot[T]{
 		Logger:  logger,
 		Config:  config,
 		OAuth:   oauth2.New(config.ClientID, config.Secret, oauth2.WithLogger(logger)),
 		Version: version,
-		Handler: handler.New(logger),
+		Handler: h,
 	}
+	if config.Dislog.Enabled {
+		h.AddPanicReporter(b.reportPanic)
+	}
+	return b
+}
+
+// パニックの報告をDislogのWebhookに送る
+func (b *Bot[T]) reportPanic(report *handler.PanicReport) {
+	if b.Client == nil {
+		return
+	}
+	embeds := traceEmbed(discord.LocaleJapanese, report, report.Stack)
+	embeds[0].Title = "💥 " + report.Source
+	// 送信を待つとパニックしたハンダラのエラー応答が遅れるため非同期で送る
+	go func() {
+		if _, err := b.Client.Rest().CreateWebhookMessage(b.Config.Dislog.WebhookID, b.Config.Dislog.WebhookToken, discord.WebhookMessageCreate{
+			Embeds: embeds,
+		}, false, 0); err != nil {
+			b.Logger.Errorf("Failed to send panic report: %s", err)
+		}
+	}()
 }
 
 type Bot[T any] struct {
@@ -49,14 +74,21 @@ type Bot[T any] struct {
 	Self    T
 }
 
+// イベントごとにゴルーチンで処理する
+// HandlerのStartDispatcherを使う場合は先に呼び出しておくこと
 func (b *Bot[T]) SetupBot(listeners ...bot.EventListener) {
+	eventManagerOpts := []bot.EventManagerConfigOpt{bot.WithListeners(listeners...), bot.WithEventManagerLogger(b.Logger), bot.WithGatewayHandlers(handlers.GetGatewayHandlers())}
+	// ワーカープールを使う場合は受け取った順にキューへ入れるため、イベントごとのゴルーチンは作らない
+	if _, dispatching := b.Handler.DispatcherStats(); !dispatching {
+		eventManagerOpts = append(eventManagerOpts, bot.WithAsyncEventsEnabled())
+	}
 	var err error
 	b.Client, err = disgo.New(b.Config.Token,
 		bot.WithLogger(b.Logger),
 		bot.WithCacheConfigOpts(cache.WithCaches(cache.FlagsAll)),
 		bot.WithShardManagerConfigOpts(sharding.WithAutoScaling(true), sharding.WithGatewayConfigOpts(gateway.WithIntents(gateway.IntentsAll), gateway.WithAutoReconnect(true), gateway.WithLogger(b.Logger))),
 		bot.WithMemberChunkingFilter(bot.MemberChunkingFilterAll),
-		bot.WithEventManagerConfigOpts(bot.WithAsyncEventsEnabled(), bot.WithListeners(listeners...), bot.WithEventManagerLogger(b.Logger), bot.WithGatewayHandlers(handlers.GetGatewayHandlers())),
+		bot.WithEventManagerConfigOpts(eventManagerOpts...),
 	)
 	if err != nil {
 		b.Logger.Fatalf("botのセットアップに失敗 %s", err)
@@ -0,0 +1,302 @@
+package botlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sabafly/sabafly-lib/v2/caches"
+	"github.com/sabafly/sabafly-lib/v2/handler"
+	"github.com/sabafly/sabafly-lib/v2/handler/customid"
+	"github.com/sabafly/sabafly-lib/v2/translate"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/google/uuid"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+)
+
+// ページ送りのコンポーネント名の接頭辞
+// 各ページ送りは連番を付けた名前で登録される
+const PaginatorComponentName = "paginator"
+
+// 作成したページ送りの数
+var paginatorCount atomic.Uint64
+
+// 最後の操作からページ送りが無効になるまでの既定の時間
+const DefaultPaginatorTimeout = 5 * time.Minute
+
+var errPaginatorExpired = errors.New("paginator expired")
+
+const (
+	paginatorFirst = "first"
+	paginatorPrev  = "prev"
+	paginatorNext  = "next"
+	paginatorLast  = "last"
+	paginatorJump  = "jump"
+	paginatorPage  = "page"
+
+	// 選択メニューに表示できるページの数
+	paginatorJumpOptions = 25
+)
+
+// ページの内容
+type Page struct {
+	Content string
+	Embeds  []discord.Embed
+}
+
+// ページ送りで表示するページ
+type PageSource interface {
+	Count(ctx context.Context) (int, error)
+	Page(ctx context.Context, index int) (Page, error)
+}
+
+// あらかじめ用意されたページ
+type StaticPages []Page
+
+func (p StaticPages) Count(context.Context) (int, error) {
+	return len(p), nil
+}
+
+func (p StaticPages) Page(_ context.Context, index int) (Page, error) {
+	return p[index], nil
+}
+
+// 表示する時に読み込むページ
+type LazyPages struct {
+	Total func(ctx context.Context) (int, error)
+	Load  func(ctx context.Context, index int) (Page, error)
+}
+
+func (p LazyPages) Count(ctx context.Context) (int, error) {
+	return p.Total(ctx)
+}
+
+func (p LazyPages) Page(ctx context.Context, index int) (Page, error) {
+	return p.Load(ctx, index)
+}
+
+type paginatorState struct {
+	mu     sync.Mutex
+	source PageSource
+	index  int
+	userID snowflake.ID
+	locale discord.Locale
+}
+
+// ボタンと選択メニューでページを切り替えるメッセージ
+// 操作できるのは作成したユーザーだけで、最後の操作から一定時間経つと無効になる
+type Paginator struct {
+	handler *handler.Handler
+	name    string
+	states  *caches.CacheManager[*paginatorState]
+}
+
+// ページ送りを作成し、コンポーネントをハンダラに登録する
+// 同じハンダラに複数作成しても互いのコンポーネントを置き換えない
+// timeoutが0の場合はDefaultPaginatorTimeout
+func NewPaginator(h *handler.Handler, timeout time.Duration) *Paginator {
+	if timeout <= 0 {
+		timeout = DefaultPaginatorTimeout
+	}
+	p := &Paginator{
+		handler: h,
+		name:    fmt.Sprintf("%s-%d", PaginatorComponentName, paginatorCount.Add(1)),
+		states:  caches.NewCacheManager[*paginatorState](&timeout),
+	}
+	h.AddComponent(p.component())
+	return p
+}
+
+// 最初のページを表示するメッセージを作成する
+func (p *Paginator) Create(ctx context.Context, userID snowflake.ID, locale discord.Locale, source PageSource) (discord.MessageCreate, error) {
+	key := uuid.New()
+	state := &paginatorState{
+		source: source,
+		userID: userID,
+		locale: locale,
+	}
+	count, err := source.Count(ctx)
+	if err != nil {
+		return discord.MessageCreate{}, err
+	}
+	page, components, err := p.render(ctx, key, state, count)
+	if err != nil {
+		return discord.MessageCreate{}, err
+	}
+	if len(components) != 0 {
+		p.states.Set(key.String(), state)
+	}
+	return discord.MessageCreate{
+		Content:    page.Content,
+		Embeds:     page.Embeds,
+		Components: components,
+	}, nil
+}
+
+func (p *Paginator) component() handler.Component {
+	handle := p.handle
+	return handler.Component{
+		Name: p.name,
+		DeferUpdate: map[string]bool{
+			paginatorFirst: true,
+			paginatorPrev:  true,
+			paginatorNext:  true,
+			paginatorLast:  true,
+			paginatorJump:  true,
+		},
+		ContextHandler: map[string]handler.ComponentContextHandler{
+			paginatorFirst: handle,
+			paginatorPrev:  handle,
+			paginatorNext:  handle,
+			paginatorLast:  handle,
+			paginatorJump:  handle,
+		},
+	}
+}
+
+func (p *Paginator) handle(ctx context.Context, event *events.ComponentInteractionCreate) error {
+	id, ok := handler.CustomID(ctx)
+	if !ok {
+		return nil
+	}
+	key, err := id.UUID(0)
+	if err != nil {
+		return err
+	}
+	state, err := p.state(key, event.User().ID)
+	if errors.Is(err, errPaginatorExpired) {
+		// 期限切れのページ送りはコンポーネントを外す
+		return event.UpdateMessage(discord.MessageUpdate{Components: &[]discord.ContainerComponent{}})
+	}
+	if err != nil {
+		return err
+	}
+	var values []string
+	if id.Sub == paginatorJump {
+		if values = event.StringSelectMenuInteractionData().Values; len(values) == 0 {
+			return nil
+		}
+	}
+	page, components, err := p.navigate(ctx, key, state, id.Sub, values)
+	if err != nil {
+		return err
+	}
+	return event.UpdateMessage(discord.MessageUpdate{
+		Content:    &page.Content,
+		Embeds:     &page.Embeds,
+		Components: &components,
+	})
+}
+
+// 操作したユーザーのページ送りの状態を返す
+func (p *Paginator) state(key uuid.UUID, userID snowflake.ID) (*paginatorState, error) {
+	state, err := p.states.Get(key.String())
+	if err != nil {
+		return nil, errPaginatorExpired
+	}
+	if userID != state.userID {
+		return nil, handler.PermissionDenied("paginator_not_owner")
+	}
+	return state, nil
+}
+
+// 操作に応じてページを移動し、表示するページとナビゲーションを作成する
+func (p *Paginator) navigate(ctx context.Context, key uuid.UUID, state *paginatorState, sub string, values []string) (Page, []discord.ContainerComponent, error) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	count, err := state.source.Count(ctx)
+	if err != nil {
+		return Page{}, nil, err
+	}
+	switch sub {
+	case paginatorFirst:
+		state.index = 0
+	case paginatorPrev:
+		state.index--
+	case paginatorNext:
+		state.index++
+	case paginatorLast:
+		state.index = count - 1
+	case paginatorJump:
+		if state.index, err = strconv.Atoi(values[0]); err != nil {
+			return Page{}, nil, err
+		}
+	}
+	page, components, err := p.render(ctx, key, state, count)
+	if err != nil {
+		return Page{}, nil, err
+	}
+	// 操作されたので有効期限を延ばす
+	p.states.Set(key.String(), state)
+	return page, components, nil
+}
+
+// 現在のページとナビゲーションを作成する
+// ページ数は呼び出し側で一度だけ数える
+func (p *Paginator) render(ctx context.Context, key uuid.UUID, state *paginatorState, count int) (Page, []discord.ContainerComponent, error) {
+	if count == 0 {
+		return Page{}, nil, handler.NotFound("paginator_empty")
+	}
+	state.index = max(0, min(state.index, count-1))
+	page, err := state.source.Page(ctx, state.index)
+	if err != nil {
+		return Page{}, nil, err
+	}
+	page.Embeds = SetEmbedsProperties(append([]discord.Embed{}, page.Embeds...))
+	if count == 1 {
+		return page, nil, nil
+	}
+
+	ids := map[string]string{}
+	for _, sub := range []string{paginatorFirst, paginatorPrev, paginatorPage, paginatorNext, paginatorLast, paginatorJump} {
+		if ids[sub], err = p.handler.EncodeCustomID(customid.New(p.name, sub).UUID(key)); err != nil {
+			return Page{}, nil, err
+		}
+	}
+	first, last := state.index == 0, state.index == count-1
+	components := []discord.ContainerComponent{
+		discord.NewActionRow(
+			discord.NewSecondaryButton(paginatorLabel(state.locale, paginatorFirst, "«"), ids[paginatorFirst]).WithDisabled(first),
+			discord.NewSecondaryButton(paginatorLabel(state.locale, paginatorPrev, "‹"), ids[paginatorPrev]).WithDisabled(first),
+			discord.NewSecondaryButton(paginatorPageLabel(state.locale, state.index, count), ids[paginatorPage]).AsDisabled(),
+			discord.NewSecondaryButton(paginatorLabel(state.locale, paginatorNext, "›"), ids[paginatorNext]).WithDisabled(last),
+			discord.NewSecondaryButton(paginatorLabel(state.locale, paginatorLast, "»"), ids[paginatorLast]).WithDisabled(last),
+		),
+	}
+	if count > 2 {
+		components = append(components, discord.NewActionRow(
+			discord.NewStringSelectMenu(ids[paginatorJump], paginatorLabel(state.locale, paginatorJump, "ページに移動"), paginatorJumpOptionsOf(state.locale, state.index, count)...),
+		))
+	}
+	return page, components, nil
+}
+
+// 現在のページの前後のページを選択肢にする
+func paginatorJumpOptionsOf(locale discord.Locale, index, count int) []discord.StringSelectMenuOption {
+	start := max(0, min(index-paginatorJumpOptions/2, count-paginatorJumpOptions))
+	end := min(count, start+paginatorJumpOptions)
+	options := make([]discord.StringSelectMenuOption, 0, end-start)
+	for i := start; i < end; i++ {
+		option := discord.NewStringSelectMenuOption(paginatorPageLabel(locale, i, count), strconv.Itoa(i))
+		option.Default = i == index
+		options = append(options, option)
+	}
+	return options
+}
+
+func paginatorLabel(locale discord.Locale, name, fallback string) string {
+	return translate.Message(locale, "paginator_"+name, translate.WithFallback(fallback))
+}
+
+func paginatorPageLabel(locale discord.Locale, index, count int) string {
+	return translate.Message(locale, "paginator_page",
+		translate.WithTemplate(map[string]any{"Page": index + 1, "Total": count}),
+		translate.WithFallback(fmt.Sprintf("%d / %d", index+1, count)),
+	)
+}
@@ -0,0 +1,162 @@
+package botlib
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/log"
+	"github.com/google/uuid"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-lib/v2/handler"
+	"github.com/sabafly/sabafly-lib/v2/handler/customid"
+)
+
+func newTestPaginator(timeout time.Duration, source PageSource) (*Paginator, uuid.UUID, *paginatorState) {
+	p := NewPaginator(handler.New(log.Default()), timeout)
+	key := uuid.New()
+	state := &paginatorState{source: source, userID: 1, locale: discord.LocaleJapanese}
+	p.states.Set(key.String(), state)
+	return p, key, state
+}
+
+func TestPaginatorNavigate(t *testing.T) {
+	pages := StaticPages{{Content: "a"}, {Content: "b"}, {Content: "c"}}
+	p, key, state := newTestPaginator(0, pages)
+	ctx := context.Background()
+	tests := []struct {
+		sub    string
+		values []string
+		want   string
+	}{
+		{paginatorPrev, nil, "a"},
+		{paginatorNext, nil, "b"},
+		{paginatorLast, nil, "c"},
+		{paginatorNext, nil, "c"},
+		{paginatorJump, []string{"1"}, "b"},
+		{paginatorJump, []string{"99"}, "c"},
+		{paginatorJump, []string{"-1"}, "a"},
+		{paginatorFirst, nil, "a"},
+	}
+	for _, tt := range tests {
+		page, components, err := p.navigate(ctx, key, state, tt.sub, tt.values)
+		if err != nil {
+			t.Fatalf("%s %v: %s", tt.sub, tt.values, err)
+		}
+		if page.Content != tt.want {
+			t.Errorf("%s %v: expected page %q, got %q", tt.sub, tt.values, tt.want, page.Content)
+		}
+		if len(components) != 2 {
+			t.Errorf("%s %v: expected buttons and a menu, got %d rows", tt.sub, tt.values, len(components))
+		}
+	}
+}
+
+func TestPaginatorsOnSameHandler(t *testing.T) {
+	h := handler.New(log.Default())
+	first, second := NewPaginator(h, 0), NewPaginator(h, 0)
+	if first.name == second.name {
+		t.Fatalf("expected distinct component names, got %q", first.name)
+	}
+	if len(h.Components) != 2 {
+		t.Fatalf("expected both paginators to be registered, got %d", len(h.Components))
+	}
+	// 各ページ送りのボタンは自身のコンポーネントに送られる
+	pages := StaticPages{{Content: "a"}, {Content: "b"}}
+	for _, p := range []*Paginator{first, second} {
+		message, err := p.Create(context.Background(), 1, discord.LocaleJapanese, pages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		button := message.Components[0].(discord.ActionRowComponent).Components()[0].(discord.ButtonComponent)
+		id, err := customid.Decode(button.CustomID, h.CustomIDKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id.Name != p.name {
+			t.Errorf("expected custom id for %q, got %q", p.name, id.Name)
+		}
+		key, err := id.UUID(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.state(key, 1); err != nil {
+			t.Errorf("expected %q to hold its own state, got %v", p.name, err)
+		}
+	}
+}
+
+func TestPaginatorCountsOnce(t *testing.T) {
+	var counted int
+	source := LazyPages{
+		Total: func(context.Context) (int, error) {
+			counted++
+			return 2, nil
+		},
+		Load: func(_ context.Context, index int) (Page, error) {
+			return Page{Content: strconv.Itoa(index)}, nil
+		},
+	}
+	p, key, state := newTestPaginator(0, source)
+	if _, _, err := p.navigate(context.Background(), key, state, paginatorNext, nil); err != nil {
+		t.Fatal(err)
+	}
+	if counted != 1 {
+		t.Errorf("expected pages to be counted once per click, got %d", counted)
+	}
+}
+
+func TestPaginatorState(t *testing.T) {
+	p, key, state := newTestPaginator(20*time.Millisecond, StaticPages{{}, {}})
+	if got, err := p.state(key, 1); err != nil || got != state {
+		t.Fatalf("expected the owner to get the state, got %v %v", got, err)
+	}
+	_, err := p.state(key, 2)
+	var e *handler.Error
+	if !errors.As(err, &e) || e.Kind != handler.ErrorKindPermission {
+		t.Errorf("expected permission error for other users, got %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, err := p.state(key, 1); !errors.Is(err, errPaginatorExpired) {
+		t.Errorf("expected expired paginator, got %v", err)
+	}
+}
+
+func TestPaginatorSinglePage(t *testing.T) {
+	p := NewPaginator(handler.New(log.Default()), 0)
+	message, err := p.Create(context.Background(), 1, discord.LocaleJapanese, StaticPages{{Content: "only"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Content != "only" || len(message.Components) != 0 {
+		t.Errorf("expected a single page without components, got %+v", message)
+	}
+	if _, err := p.Create(context.Background(), 1, discord.LocaleJapanese, StaticPages{}); err == nil {
+		t.Error("expected an error for no pages")
+	}
+}
+
+func TestPaginatorJumpOptions(t *testing.T) {
+	tests := []struct {
+		index, count int
+		first, last  int
+	}{
+		{0, 3, 0, 2},
+		{0, 100, 0, 24},
+		{50, 100, 38, 62},
+		{99, 100, 75, 99},
+		{10, 25, 0, 24},
+	}
+	for _, tt := range tests {
+		options := paginatorJumpOptionsOf(discord.LocaleJapanese, tt.index, tt.count)
+		if len(options) != tt.last-tt.first+1 || options[0].Value != strconv.Itoa(tt.first) || options[len(options)-1].Value != strconv.Itoa(tt.last) {
+			t.Errorf("%d/%d: expected pages %d-%d, got %d options", tt.index, tt.count, tt.first, tt.last, len(options))
+			continue
+		}
+		if !options[tt.index-tt.first].Default {
+			t.Errorf("%d/%d: expected the current page to be selected", tt.index, tt.count)
+		}
+	}
+}
@@ -1,14 +1,17 @@
 package botlib
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"runtime/debug"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/sabafly/sabafly-lib/v2/emoji"
+	"github.com/sabafly/sabafly-lib/v2/handler"
 	"github.com/sabafly/sabafly-lib/v2/translate"
 
 	"github.com/disgoorg/json"
@@ -173,6 +176,27 @@ func ReturnErrMessage(interaction responsibleInteraction, tr string, opts ...Ret
 	return nil
 }
 
+// ハンダラからのエラーメッセージをReturnErrMessageで返す
+func handlerErrorMessage(interaction handler.Responder, tr string, ephemeral bool, data ...any) error {
+	return ReturnErrMessage(interaction, tr, WithEphemeral(ephemeral), WithTranslateData(data...))
+}
+
+// ハンダラのエラーを種類に応じてErrorTraceEmbedかErrorMessageEmbedにする
+// パニックの場合は発生時のスタックトレースを使う
+func handlerErrorEmbeds(locale discord.Locale, err *handler.Error) []discord.Embed {
+	if err.Kind == handler.ErrorKindInternal {
+		var report *handler.PanicReport
+		if errors.As(err, &report) {
+			return traceEmbed(locale, report, report.Stack)
+		}
+		if err.Err != nil {
+			return ErrorTraceEmbed(locale, err.Err)
+		}
+		return ErrorTraceEmbed(locale, err)
+	}
+	return ErrorMessageEmbed(locale, err.Key, WithTranslateData(err.Data))
+}
+
 // エラーメッセージ埋め込みを作成する
 func ErrorMessageEmbed(locale discord.Locale, t string, opts ...ReturnErrOption) []discord.Embed {
 	cfg := new(ReturnErrCfg)
@@ -196,11 +220,20 @@ func ErrorMessageEmbed(locale discord.Locale, t string, opts ...ReturnErrOption)
 
 // エラートレース埋め込みを作成する
 func ErrorTraceEmbed(locale discord.Locale, err error) []discord.Embed {
-	stack := debug.Stack()
+	return traceEmbed(locale, err, debug.Stack())
+}
+
+// 埋め込みの説明の最大文字数
+const maxEmbedDescription = 4096
+
+func traceEmbed(locale discord.Locale, err error, stack []byte) []discord.Embed {
+	// エラーが長い場合もコードブロックが閉じるようにスタックトレースの方を削る
+	message := truncateRunes(err.Error(), maxEmbedDescription/4)
+	trace := truncateRunes(string(stack), maxEmbedDescription-utf8.RuneCountInString(message)-len("\r``````"))
 	embeds := []discord.Embed{
 		{
 			Title:       "💥" + translate.Message(locale, "error_occurred_embed_message", translate.WithFallback("エラーが発生しました")),
-			Description: fmt.Sprintf("%s\r```%s```", err, string(stack)),
+			Description: fmt.Sprintf("%s\r```%s```", message, trace),
 			Color:       0xff0000,
 		},
 	}
@@ -208,6 +241,14 @@ func ErrorTraceEmbed(locale discord.Locale, err error) []discord.Embed {
 	return embeds
 }
 
+// 文字列をn文字までに切り詰める
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
 // 渡されたステータスの絵文字を返す
 func StatusString(status discord.OnlineStatus) (str string) {
 	switch status {
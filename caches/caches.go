@@ -65,18 +65,23 @@ func (ch *Cache[T]) newContext(parent *CacheManager[T], id string, timeout *time
 		ch.ctx, ch.del = context.WithCancel(context.WithValue(context.WithValue(context.Background(), keyID, id), keyParent, parent))
 	}
 
-	go ch.closer()
+	go ch.closer(ch.ctx)
 
 	return ch.ctx, ch.del
 }
 
 // クローズするやつ
 // クローズするときは必ずLockしてから
-func (ch *Cache[T]) closer() {
-	<-ch.ctx.Done()
-	key := ch.ctx.Value(keyID).(string)
-	parent := ch.ctx.Value(keyParent).(*CacheManager[T])
-	delete(parent.caches, key)
+func (ch *Cache[T]) closer(ctx context.Context) {
+	<-ctx.Done()
+	key := ctx.Value(keyID).(string)
+	parent := ctx.Value(keyParent).(*CacheManager[T])
+	parent.sync.Lock()
+	defer parent.sync.Unlock()
+	// 同じキーで上書きされている場合は削除しない
+	if c, ok := parent.caches[key]; ok && c.ctx == ctx {
+		delete(parent.caches, key)
+	}
 }
 
 // 指定されたキーで保存します
@@ -84,6 +89,9 @@ func (c *CacheManager[T]) Set(key string, v T) {
 	c.sync.Lock()
 	defer c.sync.Unlock()
 
+	if old, ok := c.caches[key]; ok {
+		old.del()
+	}
 	cache := Cache[T]{ID: key, Data: v}
 	cache.ctx, cache.del = cache.newContext(c, key, c.timeOut)
 
@@ -120,7 +128,10 @@ func (c *CacheManager[T]) Delete(key string) {
 	c.sync.Lock()
 	defer c.sync.Unlock()
 
-	c.caches[key].del()
+	if cache, ok := c.caches[key]; ok {
+		cache.del()
+		delete(c.caches, key)
+	}
 }
 
 // for k, v := range cache { f(k, v) } と同義
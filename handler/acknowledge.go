@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/log"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+	"github.com/sabafly/sabafly-disgo/rest"
+)
+
+// 自動で遅延応答を送るまでの既定の時間
+const DefaultAutoDeferTimeout = 2500 * time.Millisecond
+
+var ErrAlreadyAcknowledged = errors.New("interaction already acknowledged")
+
+type ackTrackerKey struct{}
+
+type trackedInteraction interface {
+	ApplicationID() snowflake.ID
+	Token() string
+	CreatedAt() time.Time
+}
+
+// 応答の変換に使うREST
+type interactionRest interface {
+	UpdateInteractionResponse(applicationID snowflake.ID, interactionToken string, messageUpdate discord.MessageUpdate, opts ...rest.RequestOpt) (*discord.Message, error)
+	CreateFollowupMessage(applicationID snowflake.ID, interactionToken string, messageCreate discord.MessageCreate, opts ...rest.RequestOpt) (*discord.Message, error)
+	UpdateFollowupMessage(applicationID snowflake.ID, interactionToken string, messageID snowflake.ID, messageUpdate discord.MessageUpdate, opts ...rest.RequestOpt) (*discord.Message, error)
+}
+
+// インタラクションへの応答を記録する
+type ackTracker struct {
+	// 応答を送る処理を直列にする
+	// 通信中も保持するので、状態の読み書きにはmuを使う
+	sendMu sync.Mutex
+	mu     sync.Mutex
+
+	logger       log.Logger
+	rest         interactionRest
+	interaction  trackedInteraction
+	original     events.InteractionResponderFunc
+	timer        *time.Timer
+	acknowledged bool
+	// 以降の応答をフォローアップや応答の編集に変換する
+	convert bool
+	// 最初の応答の種類
+	responseType discord.InteractionResponseType
+}
+
+func (h *Handler) newAckTracker(client bot.Client, interaction trackedInteraction, original events.InteractionResponderFunc) *ackTracker {
+	t := &ackTracker{
+		logger:      h.Logger,
+		interaction: interaction,
+		original:    original,
+	}
+	if client != nil {
+		t.rest = client.Rest()
+	}
+	return t
+}
+
+func (t *ackTracker) state() (acknowledged, convert bool, responseType discord.InteractionResponseType) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.acknowledged, t.convert, t.responseType
+}
+
+// 応答したことを記録する
+func (t *ackTracker) acknowledge(responseType discord.InteractionResponseType, convert bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.acknowledged = true
+	t.convert = t.convert || convert
+	t.responseType = responseType
+	t.stop()
+}
+
+// イベントのRespondを置き換える
+// 自動で遅延応答を送った後の応答はフォローアップやオリジナルの応答の編集に変換する
+func (t *ackTracker) respond(responseType discord.InteractionResponseType, data discord.InteractionResponseData, opts ...rest.RequestOpt) error {
+	t.sendMu.Lock()
+	defer t.sendMu.Unlock()
+	acknowledged, convert, _ := t.state()
+	if acknowledged {
+		if convert {
+			return t.followup(responseType, data, opts...)
+		}
+		return ErrAlreadyAcknowledged
+	}
+	if err := t.original(responseType, data, opts...); err != nil {
+		return err
+	}
+	t.acknowledge(responseType, false)
+	return nil
+}
+
+// 応答の状態に合わせてメッセージを送る
+// 未応答なら応答を作成し、考え中の遅延応答ならそれを編集し、それ以外はフォローアップを送る
+func (t *ackTracker) reply(message discord.MessageCreate) error {
+	_, err := t.send(message)
+	return err
+}
+
+// replyと同じようにメッセージを送り、送ったメッセージを編集する関数を返す
+func (t *ackTracker) send(message discord.MessageCreate) (func(discord.MessageUpdate) error, error) {
+	t.sendMu.Lock()
+	defer t.sendMu.Unlock()
+	updateOriginal := func(messageUpdate discord.MessageUpdate) error {
+		_, err := t.rest.UpdateInteractionResponse(t.interaction.ApplicationID(), t.interaction.Token(), messageUpdate)
+		return err
+	}
+	acknowledged, _, responseType := t.state()
+	if !acknowledged {
+		if err := t.original(discord.InteractionResponseTypeCreateMessage, message); err != nil {
+			return nil, err
+		}
+		t.acknowledge(discord.InteractionResponseTypeCreateMessage, false)
+		return updateOriginal, nil
+	}
+	if responseType == discord.InteractionResponseTypeDeferredCreateMessage {
+		if err := updateOriginal(discord.MessageUpdate{
+			Content:    &message.Content,
+			Embeds:     &message.Embeds,
+			Components: &message.Components,
+		}); err != nil {
+			return nil, err
+		}
+		// 考え中の表示は消えたので以降はフォローアップを送る
+		t.mu.Lock()
+		t.responseType = discord.InteractionResponseTypeCreateMessage
+		t.mu.Unlock()
+		return updateOriginal, nil
+	}
+	m, err := t.rest.CreateFollowupMessage(t.interaction.ApplicationID(), t.interaction.Token(), message)
+	if err != nil {
+		return nil, err
+	}
+	return func(messageUpdate discord.MessageUpdate) error {
+		_, err := t.rest.UpdateFollowupMessage(t.interaction.ApplicationID(), t.interaction.Token(), m.ID, messageUpdate)
+		return err
+	}, nil
+}
+
+func (t *ackTracker) followup(responseType discord.InteractionResponseType, data discord.InteractionResponseData, opts ...rest.RequestOpt) error {
+	switch responseType {
+	case discord.InteractionResponseTypeDeferredCreateMessage, discord.InteractionResponseTypeDeferredUpdateMessage:
+		return nil
+	case discord.InteractionResponseTypeCreateMessage:
+		messageCreate, ok := data.(discord.MessageCreate)
+		if !ok {
+			return fmt.Errorf("unexpected response data %T", data)
+		}
+		_, err := t.rest.CreateFollowupMessage(t.interaction.ApplicationID(), t.interaction.Token(), messageCreate, opts...)
+		return err
+	case discord.InteractionResponseTypeUpdateMessage:
+		messageUpdate, ok := data.(discord.MessageUpdate)
+		if !ok {
+			return fmt.Errorf("unexpected response data %T", data)
+		}
+		_, err := t.rest.UpdateInteractionResponse(t.interaction.ApplicationID(), t.interaction.Token(), messageUpdate, opts...)
+		return err
+	default:
+		return fmt.Errorf("%w: response type %d cannot be sent after deferring", ErrAlreadyAcknowledged, responseType)
+	}
+}
+
+// 期限までに応答がなければ遅延応答を送る
+func (t *ackTracker) autoDefer(timeout time.Duration, responseType discord.InteractionResponseType, data discord.InteractionResponseData) {
+	if timeout < 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.acknowledged {
+		return
+	}
+	t.timer = time.AfterFunc(time.Until(t.interaction.CreatedAt().Add(timeout)), func() {
+		// 応答を送っている途中なら遅延応答は不要なので待たない
+		if !t.sendMu.TryLock() {
+			return
+		}
+		defer t.sendMu.Unlock()
+		if acknowledged, _, _ := t.state(); acknowledged {
+			return
+		}
+		if err := t.original(responseType, data); err != nil {
+			t.logger.Errorf("Failed to defer interaction: %s", err)
+			return
+		}
+		t.acknowledge(responseType, true)
+	})
+}
+
+// 以降の応答をフォローアップや応答の編集に変換する
+func (t *ackTracker) convertResponses() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.convert = true
+}
+
+func (t *ackTracker) stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+func (t *ackTracker) Acknowledged() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.acknowledged
+}
+
+func (h *Handler) autoDeferTimeout() time.Duration {
+	if h.AutoDeferTimeout == 0 {
+		return DefaultAutoDeferTimeout
+	}
+	return h.AutoDeferTimeout
+}
+
+func deferCreateMessageData(ephemeral bool) discord.InteractionResponseData {
+	if ephemeral {
+		return discord.MessageCreate{Flags: discord.MessageFlagEphemeral}
+	}
+	return nil
+}
+
+func withAckTracker(ctx context.Context, tracker *ackTracker) context.Context {
+	return context.WithValue(ctx, ackTrackerKey{}, tracker)
+}
+
+// インタラクションに応答済みかどうかを返す
+func IsAcknowledged(ctx context.Context) bool {
+	tracker, ok := ctx.Value(ackTrackerKey{}).(*ackTracker)
+	return ok && tracker.Acknowledged()
+}
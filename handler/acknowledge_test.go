@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/log"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/rest"
+)
+
+// 呼び出されたRESTを記録する
+type testInteractionRest struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *testInteractionRest) record(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *testInteractionRest) Calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.calls...)
+}
+
+func (r *testInteractionRest) UpdateInteractionResponse(snowflake.ID, string, discord.MessageUpdate, ...rest.RequestOpt) (*discord.Message, error) {
+	r.record("update original")
+	return &discord.Message{}, nil
+}
+
+func (r *testInteractionRest) CreateFollowupMessage(snowflake.ID, string, discord.MessageCreate, ...rest.RequestOpt) (*discord.Message, error) {
+	r.record("create followup")
+	return &discord.Message{ID: 1}, nil
+}
+
+func (r *testInteractionRest) UpdateFollowupMessage(snowflake.ID, string, snowflake.ID, discord.MessageUpdate, ...rest.RequestOpt) (*discord.Message, error) {
+	r.record("update followup")
+	return &discord.Message{ID: 1}, nil
+}
+
+// 元のRespondの呼び出しを記録する
+type testResponder struct {
+	mu        sync.Mutex
+	responses []discord.InteractionResponseType
+	data      []discord.InteractionResponseData
+	// nilでない場合は閉じられるまで応答を止める
+	block chan struct{}
+}
+
+func (r *testResponder) respond(responseType discord.InteractionResponseType, data discord.InteractionResponseData, _ ...rest.RequestOpt) error {
+	if r.block != nil {
+		<-r.block
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses = append(r.responses, responseType)
+	r.data = append(r.data, data)
+	return nil
+}
+
+func (r *testResponder) Responses() []discord.InteractionResponseType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]discord.InteractionResponseType{}, r.responses...)
+}
+
+func newTestAckTracker(responder *testResponder) (*ackTracker, *testInteractionRest) {
+	h := New(log.Default())
+	tracker := h.newAckTracker(nil, testInteraction{}, responder.respond)
+	r := &testInteractionRest{}
+	tracker.rest = r
+	return tracker, r
+}
+
+func waitAcknowledged(t *testing.T, tracker *ackTracker) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !tracker.Acknowledged() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected interaction to be deferred")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAutoDeferConvertsResponses(t *testing.T) {
+	responder := &testResponder{}
+	tracker, r := newTestAckTracker(responder)
+	tracker.autoDefer(time.Millisecond, discord.InteractionResponseTypeDeferredCreateMessage, nil)
+	waitAcknowledged(t, tracker)
+
+	if err := tracker.respond(discord.InteractionResponseTypeCreateMessage, discord.MessageCreate{Content: "late"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.respond(discord.InteractionResponseTypeUpdateMessage, discord.MessageUpdate{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.respond(discord.InteractionResponseTypeDeferredCreateMessage, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.respond(discord.InteractionResponseTypeModal, discord.ModalCreate{}); err == nil {
+		t.Error("expected modal after deferring to fail")
+	}
+	if responses := responder.Responses(); len(responses) != 1 || responses[0] != discord.InteractionResponseTypeDeferredCreateMessage {
+		t.Errorf("expected a single deferred response, got %v", responses)
+	}
+	if calls := r.Calls(); len(calls) != 2 || calls[0] != "create followup" || calls[1] != "update original" {
+		t.Errorf("unexpected rest calls %q", calls)
+	}
+}
+
+func TestAutoDeferSkippedAfterResponse(t *testing.T) {
+	responder := &testResponder{}
+	tracker, _ := newTestAckTracker(responder)
+	tracker.autoDefer(20*time.Millisecond, discord.InteractionResponseTypeDeferredCreateMessage, nil)
+	if err := tracker.respond(discord.InteractionResponseTypeCreateMessage, discord.MessageCreate{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if responses := responder.Responses(); len(responses) != 1 {
+		t.Errorf("expected no deferred response, got %v", responses)
+	}
+	if err := tracker.respond(discord.InteractionResponseTypeCreateMessage, discord.MessageCreate{}); err != ErrAlreadyAcknowledged {
+		t.Errorf("expected ErrAlreadyAcknowledged, got %v", err)
+	}
+}
+
+func TestAutoDeferDoesNotWaitForSlowResponse(t *testing.T) {
+	responder := &testResponder{block: make(chan struct{})}
+	tracker, _ := newTestAckTracker(responder)
+	tracker.autoDefer(time.Millisecond, discord.InteractionResponseTypeDeferredCreateMessage, nil)
+	done := make(chan error, 1)
+	go func() {
+		done <- tracker.respond(discord.InteractionResponseTypeCreateMessage, discord.MessageCreate{})
+	}()
+
+	// 通信中でも状態は読める
+	time.Sleep(20 * time.Millisecond)
+	checked := make(chan bool, 1)
+	go func() { checked <- tracker.Acknowledged() }()
+	select {
+	case acknowledged := <-checked:
+		if acknowledged {
+			t.Error("expected interaction not to be acknowledged yet")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Acknowledged blocked behind the response")
+	}
+
+	close(responder.block)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if responses := responder.Responses(); len(responses) != 1 || responses[0] != discord.InteractionResponseTypeCreateMessage {
+		t.Errorf("expected only the slow response, got %v", responses)
+	}
+}
+
+func TestSendAfterAutoDefer(t *testing.T) {
+	responder := &testResponder{}
+	tracker, r := newTestAckTracker(responder)
+	tracker.autoDefer(time.Millisecond, discord.InteractionResponseTypeDeferredCreateMessage, nil)
+	waitAcknowledged(t, tracker)
+
+	// 考え中の応答を編集し、次からはフォローアップを送る
+	update, err := tracker.send(discord.MessageCreate{Content: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := update(discord.MessageUpdate{}); err != nil {
+		t.Fatal(err)
+	}
+	update, err = tracker.send(discord.MessageCreate{Content: "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := update(discord.MessageUpdate{}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"update original", "update original", "create followup", "update followup"}
+	calls := r.Calls()
+	if len(calls) != len(want) {
+		t.Fatalf("expected rest calls %q, got %q", want, calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("expected rest calls %q, got %q", want, calls)
+		}
+	}
+}
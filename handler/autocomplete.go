@@ -0,0 +1,287 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+	"github.com/sabafly/sabafly-lib/v2/caches"
+	"github.com/sabafly/sabafly-lib/v2/translate"
+)
+
+// オートコンプリートで返せる候補の最大数
+const MaxAutocompleteChoices = 25
+
+// 候補の表示名の最大文字数
+// 一つでも超えると応答全体が拒否される
+const maxAutocompleteChoiceName = 100
+
+// オートコンプリートの候補
+type AutocompleteCandidate struct {
+	// 表示名
+	Name string
+	// 表示名の翻訳キー
+	// 指定した場合は利用者のロケールの翻訳で照合し、各ロケールの表示名を設定する
+	NameKey string
+	// string、int、float64のいずれか
+	Value any
+	// 表示名の他に照合する語
+	Keywords []string
+}
+
+// オートコンプリートの候補を返す
+type AutocompleteSource interface {
+	Candidates(ctx context.Context, event *events.AutocompleteInteractionCreate) ([]AutocompleteCandidate, error)
+}
+
+// 固定の候補
+type StaticCandidates []AutocompleteCandidate
+
+func (c StaticCandidates) Candidates(context.Context, *events.AutocompleteInteractionCreate) ([]AutocompleteCandidate, error) {
+	return c, nil
+}
+
+// 関数で候補を返す
+type CandidatesFunc func(ctx context.Context, event *events.AutocompleteInteractionCreate) ([]AutocompleteCandidate, error)
+
+func (f CandidatesFunc) Candidates(ctx context.Context, event *events.AutocompleteInteractionCreate) ([]AutocompleteCandidate, error) {
+	return f(ctx, event)
+}
+
+type cachedCandidates struct {
+	mu      sync.Mutex
+	loading map[string]*candidatesLoad
+	key     func(event *events.AutocompleteInteractionCreate) string
+	load    CandidatesFunc
+	cache   *caches.CacheManager[[]AutocompleteCandidate]
+}
+
+// 読み込み中の候補
+type candidatesLoad struct {
+	done       chan struct{}
+	candidates []AutocompleteCandidate
+	err        error
+}
+
+// 読み込んだ候補をttlの間キャッシュする
+// keyはキャッシュのキーを返し、nilの場合は全てのイベントで共有する
+func CachedCandidates(ttl time.Duration, key func(event *events.AutocompleteInteractionCreate) string, load CandidatesFunc) AutocompleteSource {
+	return &cachedCandidates{
+		loading: map[string]*candidatesLoad{},
+		key:     key,
+		load:    load,
+		cache:   caches.NewCacheManager[[]AutocompleteCandidate](&ttl),
+	}
+}
+
+func (c *cachedCandidates) Candidates(ctx context.Context, event *events.AutocompleteInteractionCreate) ([]AutocompleteCandidate, error) {
+	var key string
+	if c.key != nil {
+		key = c.key(event)
+	}
+	// 同じキーの読み込みが終わるのを待ち、他のキーの読み込みは待たせない
+	c.mu.Lock()
+	if candidates, err := c.cache.Get(key); err == nil {
+		c.mu.Unlock()
+		return candidates, nil
+	}
+	if l, ok := c.loading[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-l.done:
+			return l.candidates, l.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	l := &candidatesLoad{done: make(chan struct{})}
+	c.loading[key] = l
+	c.mu.Unlock()
+
+	l.candidates, l.err = c.load(ctx, event)
+	c.mu.Lock()
+	if l.err == nil {
+		c.cache.Set(key, l.candidates)
+	}
+	delete(c.loading, key)
+	c.mu.Unlock()
+	close(l.done)
+	return l.candidates, l.err
+}
+
+// 候補をフォーカスされたオプションの入力で並べ替えて応答する
+type Autocomplete struct {
+	Source AutocompleteSource
+	// 前方一致する候補だけを返す
+	PrefixOnly bool
+
+	cache *caches.CacheManager[[]discord.AutocompleteChoice]
+}
+
+// オートコンプリートを作成する
+// cacheTTLの間は同じコマンドでの同じユーザーの同じ入力に同じ候補を返し、0の場合はキャッシュしない
+func NewAutocomplete(source AutocompleteSource, cacheTTL time.Duration) *Autocomplete {
+	a := &Autocomplete{Source: source}
+	if cacheTTL > 0 {
+		a.cache = caches.NewCacheManager[[]discord.AutocompleteChoice](&cacheTTL)
+	}
+	return a
+}
+
+// AutocompleteContextHandlersに登録するハンダラ
+func (a *Autocomplete) Handler() AutocompleteContextHandler {
+	return func(ctx context.Context, event *events.AutocompleteInteractionCreate) error {
+		choices, err := a.Choices(ctx, event)
+		if err != nil {
+			return err
+		}
+		return event.Result(choices)
+	}
+}
+
+// フォーカスされたオプションの入力に一致する候補を返す
+func (a *Autocomplete) Choices(ctx context.Context, event *events.AutocompleteInteractionCreate) ([]discord.AutocompleteChoice, error) {
+	option := event.Data.Focused()
+	query := autocompleteQuery(option)
+	key := autocompleteCacheKey(event, option.Name, query)
+	if a.cache != nil {
+		if choices, err := a.cache.Get(key); err == nil {
+			return choices, nil
+		}
+	}
+	candidates, err := a.Source.Candidates(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	choices := autocompleteChoices(event.Locale(), rankCandidates(event.Locale(), query, candidates, a.PrefixOnly))
+	if a.cache != nil {
+		a.cache.Set(key, choices)
+	}
+	return choices, nil
+}
+
+// 候補はコマンドとロケールによって変わるので、ユーザーと入力に加えてキーに含める
+func autocompleteCacheKey(event *events.AutocompleteInteractionCreate, option, query string) string {
+	path := buildCommandPath(event.Data.SubCommandName, event.Data.SubCommandGroupName)
+	return fmt.Sprintf("%s/%s:%s:%d:%s:%s", event.Data.CommandName, path, event.Locale(), event.User().ID, option, query)
+}
+
+// 入力された値を文字列にする
+func autocompleteQuery(option discord.AutocompleteOption) string {
+	var query string
+	if err := json.Unmarshal(option.Value, &query); err != nil {
+		query = string(option.Value)
+	}
+	return strings.TrimSpace(query)
+}
+
+// 候補の表示名
+func (c AutocompleteCandidate) localizedName(locale discord.Locale) string {
+	if c.NameKey == "" {
+		return c.Name
+	}
+	return translate.Message(locale, c.NameKey, translate.WithFallback(c.Name))
+}
+
+// 入力に一致する候補を一致度の高い順に最大MaxAutocompleteChoices件返す
+// 入力が空の場合は元の順で返す
+func rankCandidates(locale discord.Locale, query string, candidates []AutocompleteCandidate, prefixOnly bool) []AutocompleteCandidate {
+	type ranked struct {
+		candidate AutocompleteCandidate
+		score     int
+	}
+	query = strings.ToLower(query)
+	results := make([]ranked, 0, len(candidates))
+	for _, candidate := range candidates {
+		words := append([]string{candidate.localizedName(locale), candidate.Name, fmt.Sprint(candidate.Value)}, candidate.Keywords...)
+		best := -1
+		for _, word := range words {
+			best = max(best, matchScore(strings.ToLower(word), query, prefixOnly))
+		}
+		if best >= 0 {
+			results = append(results, ranked{candidate: candidate, score: best})
+		}
+	}
+	slices.SortStableFunc(results, func(a, b ranked) int {
+		return b.score - a.score
+	})
+	ranks := make([]AutocompleteCandidate, 0, min(len(results), MaxAutocompleteChoices))
+	for _, r := range results[:min(len(results), MaxAutocompleteChoices)] {
+		ranks = append(ranks, r.candidate)
+	}
+	return ranks
+}
+
+// 一致度を返す
+// 完全一致、前方一致、単語の前方一致、部分一致、飛び飛びの一致の順に高く、一致しない場合は-1
+func matchScore(word, query string, prefixOnly bool) int {
+	switch {
+	case query == "":
+		return 0
+	case word == query:
+		return 4000
+	case strings.HasPrefix(word, query):
+		return 3000 - len(word)
+	case prefixOnly:
+		return -1
+	}
+	// 前方一致ではないのでiは0より大きい
+	if i := strings.Index(word, query); i >= 0 {
+		if strings.ContainsRune(" _-.", rune(word[i-1])) {
+			return 2000 - i
+		}
+		return 1000 - i
+	}
+	// 入力の文字が順に含まれていれば一致とし、間に挟まる文字が少ないほど高くする
+	gaps, start := 0, 0
+	for _, r := range query {
+		i := strings.IndexRune(word[start:], r)
+		if i < 0 {
+			return -1
+		}
+		if start != 0 {
+			gaps += i
+		}
+		start += i + len(string(r))
+	}
+	return max(1, 500-gaps)
+}
+
+// 候補をオートコンプリートの応答にする
+func autocompleteChoices(locale discord.Locale, candidates []AutocompleteCandidate) []discord.AutocompleteChoice {
+	choices := make([]discord.AutocompleteChoice, 0, len(candidates))
+	for _, c := range candidates {
+		name := truncateRunes(c.localizedName(locale), maxAutocompleteChoiceName)
+		var localizations map[discord.Locale]string
+		if c.NameKey != "" {
+			localizations = translate.MessageMap(c.NameKey, false, translate.WithFallback(c.Name))
+			for l, n := range localizations {
+				localizations[l] = truncateRunes(n, maxAutocompleteChoiceName)
+			}
+		}
+		switch v := c.Value.(type) {
+		case int:
+			choices = append(choices, discord.AutocompleteChoiceInt{Name: name, NameLocalizations: localizations, Value: v})
+		case float64:
+			choices = append(choices, discord.AutocompleteChoiceFloat{Name: name, NameLocalizations: localizations, Value: v})
+		default:
+			choices = append(choices, discord.AutocompleteChoiceString{Name: name, NameLocalizations: localizations, Value: fmt.Sprint(v)})
+		}
+	}
+	return choices
+}
+
+// 文字列をn文字までに切り詰める
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+)
+
+func TestRankCandidates(t *testing.T) {
+	candidates := []AutocompleteCandidate{
+		{Name: "Blue", Value: "blue"},
+		{Name: "Light blue", Value: "light_blue"},
+		{Name: "Black", Value: "black"},
+		{Name: "Blurple", Value: "blurple", Keywords: []string{"discord"}},
+		{Name: "Bluebell", Value: "bluebell"},
+	}
+	names := func(candidates []AutocompleteCandidate) string {
+		var s []string
+		for _, c := range candidates {
+			s = append(s, c.Name)
+		}
+		return fmt.Sprint(s)
+	}
+
+	tests := map[string]struct {
+		query      string
+		prefixOnly bool
+		want       string
+	}{
+		"empty":       {"", false, "[Blue Light blue Black Blurple Bluebell]"},
+		"exact first": {"blue", false, "[Blue Bluebell Light blue Blurple]"},
+		"case":        {"BLUE", false, "[Blue Bluebell Light blue Blurple]"},
+		"prefix only": {"blue", true, "[Blue Bluebell]"},
+		"fuzzy":       {"blpl", false, "[Blurple]"},
+		"keyword":     {"disc", false, "[Blurple]"},
+		"value":       {"light_", false, "[Light blue]"},
+		"no match":    {"red", false, "[]"},
+	}
+	for name, tt := range tests {
+		if got := names(rankCandidates(discord.LocaleJapanese, tt.query, candidates, tt.prefixOnly)); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", name, tt.want, got)
+		}
+	}
+
+	many := make([]AutocompleteCandidate, 30)
+	for i := range many {
+		many[i] = AutocompleteCandidate{Name: fmt.Sprint("item", i), Value: i}
+	}
+	if got := rankCandidates(discord.LocaleJapanese, "item", many, false); len(got) != MaxAutocompleteChoices {
+		t.Errorf("expected %d choices, got %d", MaxAutocompleteChoices, len(got))
+	}
+	choices := autocompleteChoices(discord.LocaleJapanese, many[:1])
+	if choice, ok := choices[0].(discord.AutocompleteChoiceInt); !ok || choice.Value != 0 {
+		t.Errorf("unexpected choice %#v", choices[0])
+	}
+}
+
+func TestCachedCandidates(t *testing.T) {
+	var loads int
+	source := CachedCandidates(time.Minute, nil, func(context.Context, *events.AutocompleteInteractionCreate) ([]AutocompleteCandidate, error) {
+		loads++
+		return []AutocompleteCandidate{{Name: "a", Value: "a"}}, nil
+	})
+	for i := 0; i < 3; i++ {
+		candidates, err := source.Candidates(context.Background(), nil)
+		if err != nil || len(candidates) != 1 {
+			t.Fatalf("unexpected candidates %v %v", candidates, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("expected a single load, got %d", loads)
+	}
+}
+
+func newTestAutocompleteEvent(command string) *events.AutocompleteInteractionCreate {
+	return &events.AutocompleteInteractionCreate{
+		AutocompleteInteraction: discord.AutocompleteInteraction{
+			Data: discord.AutocompleteInteractionData{CommandName: command},
+		},
+	}
+}
+
+// 読み込みが遅いキーがあっても他のキーは待たされず、同じキーの読み込みは一度にまとめられる
+func TestCachedCandidatesPerKey(t *testing.T) {
+	release := make(chan struct{})
+	var loads atomic.Int32
+	source := CachedCandidates(time.Minute, func(event *events.AutocompleteInteractionCreate) string {
+		return event.Data.CommandName
+	}, func(_ context.Context, event *events.AutocompleteInteractionCreate) ([]AutocompleteCandidate, error) {
+		loads.Add(1)
+		if event.Data.CommandName == "slow" {
+			<-release
+		}
+		return []AutocompleteCandidate{{Name: event.Data.CommandName}}, nil
+	})
+
+	results := make(chan string, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			candidates, _ := source.Candidates(context.Background(), newTestAutocompleteEvent("slow"))
+			results <- candidates[0].Name
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := source.Candidates(context.Background(), newTestAutocompleteEvent("fast")); err != nil {
+			t.Error(err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected other keys not to wait for a slow load")
+	}
+	close(release)
+	for i := 0; i < 2; i++ {
+		if name := <-results; name != "slow" {
+			t.Errorf("unexpected candidate %q", name)
+		}
+	}
+	if n := loads.Load(); n != 2 {
+		t.Errorf("expected one load per key, got %d", n)
+	}
+}
+
+func TestAutocompleteCacheKey(t *testing.T) {
+	sub := "add"
+	a := newTestAutocompleteEvent("role")
+	b := newTestAutocompleteEvent("color")
+	c := newTestAutocompleteEvent("role")
+	c.Data.SubCommandName = &sub
+	keys := map[string]bool{}
+	for _, event := range []*events.AutocompleteInteractionCreate{a, b, c} {
+		keys[autocompleteCacheKey(event, "name", "re")] = true
+	}
+	if len(keys) != 3 {
+		t.Errorf("expected commands to have separate cache keys, got %v", keys)
+	}
+}
+
+func TestAutocompleteChoicesTruncate(t *testing.T) {
+	long := strings.Repeat("あ", maxAutocompleteChoiceName+1)
+	choices := autocompleteChoices(discord.LocaleJapanese, []AutocompleteCandidate{
+		{Name: long, Value: "long"},
+		{Name: long, NameKey: "autocomplete_test_missing", Value: 1},
+		{Name: "short", Value: "short"},
+	})
+	for i, choice := range choices {
+		var (
+			name          string
+			localizations map[discord.Locale]string
+		)
+		switch c := choice.(type) {
+		case discord.AutocompleteChoiceString:
+			name, localizations = c.Name, c.NameLocalizations
+		case discord.AutocompleteChoiceInt:
+			name, localizations = c.Name, c.NameLocalizations
+		}
+		if n := utf8.RuneCountInString(name); n > maxAutocompleteChoiceName {
+			t.Errorf("choice %d: expected name to be truncated, got %d characters", i, n)
+		}
+		for locale, localized := range localizations {
+			if n := utf8.RuneCountInString(localized); n > maxAutocompleteChoiceName {
+				t.Errorf("choice %d: expected %s name to be truncated, got %d characters", i, locale, n)
+			}
+		}
+	}
+	if name := choices[2].(discord.AutocompleteChoiceString).Name; name != "short" {
+		t.Errorf("expected short name to be kept, got %q", name)
+	}
+}
@@ -1,31 +1,135 @@
 package handler
 
-type Check[T any] func(ctx T) bool
+// チェックの結果
+type CheckResult struct {
+	Allowed bool
+	// 拒否した理由のエラーメッセージの翻訳キー
+	// 空の場合は応答せずに拒否する
+	Reason string
+	// 翻訳のテンプレートデータ
+	Data any
+}
 
-func (c Check[T]) Or(check Check[T]) Check[T] {
-	return func(ctx T) bool {
-		return c(ctx) || check(ctx)
+// 許可する
+func Allow() CheckResult {
+	return CheckResult{Allowed: true}
+}
+
+// 拒否する
+// reasonが空の場合は応答しない
+func Deny(reason string, data ...any) CheckResult {
+	r := CheckResult{Reason: reason}
+	if len(data) != 0 {
+		r.Data = data[0]
 	}
+	return r
+}
+
+// イベントを処理するかどうかを決める
+// nilのチェックは常に許可する
+type Check[T any] func(event T) CheckResult
+
+// チェックを実行する
+func (c Check[T]) Run(event T) CheckResult {
+	if c == nil {
+		return Allow()
+	}
+	return c(event)
+}
+
+// どちらかが許可すれば許可する
+func (c Check[T]) Or(check Check[T]) Check[T] {
+	return CheckAnyOf(c, check)
 }
 
+// 両方が許可すれば許可する
 func (c Check[T]) And(check Check[T]) Check[T] {
-	return func(ctx T) bool {
-		return c(ctx) && check(ctx)
+	return CheckAllOf(c, check)
+}
+
+// 条件を満たす場合に許可するチェックを作成する
+// 満たさない場合はreasonで拒否する
+func CheckIf[T any](condition func(event T) bool, reason string, data ...any) Check[T] {
+	return func(event T) CheckResult {
+		if condition(event) {
+			return Allow()
+		}
+		return Deny(reason, data...)
+	}
+}
+
+// チェックが拒否した場合に許可し、許可した場合はreasonで拒否する
+// nilのチェックは常に許可するため、常に拒否する
+func CheckNot[T any](check Check[T], reason string, data ...any) Check[T] {
+	return func(event T) CheckResult {
+		if check.Run(event).Allowed {
+			return Deny(reason, data...)
+		}
+		return Allow()
 	}
 }
 
+// いずれかのチェックが許可すれば許可する
+// 最初に許可したチェックで打ち切り、全て拒否した場合は最初の拒否の理由を返す
+// 他と同じくnilのチェックは許可したものとして扱い、チェックがない場合も許可する
 func CheckAnyOf[T any](checks ...Check[T]) Check[T] {
-	var check Check[T]
-	for _, c := range checks {
-		check = check.Or(c)
+	return func(event T) CheckResult {
+		var denied *CheckResult
+		for _, check := range checks {
+			r := check.Run(event)
+			if r.Allowed {
+				return r
+			}
+			if denied == nil {
+				denied = &r
+			}
+		}
+		if denied == nil {
+			return Allow()
+		}
+		return *denied
 	}
-	return check
 }
 
+// 全てのチェックが許可すれば許可する
+// 最初に拒否したチェックで打ち切り、その理由を返す
+// nilのチェックは無視し、チェックがない場合は許可する
 func CheckAllOf[T any](checks ...Check[T]) Check[T] {
-	var check Check[T]
-	for _, c := range checks {
-		check = check.And(c)
+	checks = nonNilChecks(checks)
+	return func(event T) CheckResult {
+		for _, check := range checks {
+			if r := check(event); !r.Allowed {
+				return r
+			}
+		}
+		return Allow()
+	}
+}
+
+func nonNilChecks[T any](checks []Check[T]) []Check[T] {
+	result := make([]Check[T], 0, len(checks))
+	for _, check := range checks {
+		if check != nil {
+			result = append(result, check)
+		}
+	}
+	return result
+}
+
+// チェックを実行し、拒否した理由があればエラーメッセージを応答する
+// 全てのチェックが許可した場合にtrueを返す
+func checkInteraction[T Responder](h *Handler, event T, checks ...Check[T]) bool {
+	for _, check := range checks {
+		r := check.Run(event)
+		if r.Allowed {
+			continue
+		}
+		if r.Reason != "" {
+			if err := h.replyErrorMessage(event, r.Reason, true, r.Data); err != nil {
+				h.Logger.Errorf("Failed to reply check denial \"%s\": %s", r.Reason, err)
+			}
+		}
+		return false
 	}
-	return check
+	return true
 }
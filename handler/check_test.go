@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/disgoorg/log"
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/events"
+)
+
+func TestCheckCombinators(t *testing.T) {
+	var calls []string
+	allow := func(name string) Check[int] {
+		return func(int) CheckResult {
+			calls = append(calls, name)
+			return Allow()
+		}
+	}
+	deny := func(name string) Check[int] {
+		return func(int) CheckResult {
+			calls = append(calls, name)
+			return Deny(name)
+		}
+	}
+
+	tests := map[string]struct {
+		check   Check[int]
+		allowed bool
+		reason  string
+		calls   []string
+	}{
+		"nil":                        {nil, true, "", nil},
+		"if":                         {CheckIf(func(i int) bool { return i == 1 }, "if"), true, "", nil},
+		"if denied":                  {CheckIf(func(i int) bool { return i == 2 }, "if"), false, "if", nil},
+		"not":                        {CheckNot(deny("a"), "not"), true, "", []string{"a"}},
+		"not denied":                 {CheckNot(allow("a"), "not"), false, "not", []string{"a"}},
+		"not nil":                    {CheckNot[int](nil, "not"), false, "not", nil},
+		"any of empty":               {CheckAnyOf[int](), true, "", nil},
+		"any of nil":                 {CheckAnyOf[int](nil, nil), true, "", nil},
+		"any of short circuits":      {CheckAnyOf(deny("a"), allow("b"), allow("c")), true, "", []string{"a", "b"}},
+		"any of nil allows":          {CheckAnyOf(deny("a"), nil, deny("b")), true, "", []string{"a"}},
+		"any of first denial reason": {CheckAnyOf(deny("a"), deny("b")), false, "a", []string{"a", "b"}},
+		"all of empty":               {CheckAllOf[int](), true, "", nil},
+		"all of nil":                 {CheckAllOf[int](nil, nil), true, "", nil},
+		"all of":                     {CheckAllOf(allow("a"), nil, allow("b")), true, "", []string{"a", "b"}},
+		"all of short circuits":      {CheckAllOf(allow("a"), deny("b"), deny("c")), false, "b", []string{"a", "b"}},
+		"or":                         {deny("a").Or(allow("b")), true, "", []string{"a", "b"}},
+		"or nil receiver":            {Check[int](nil).Or(deny("a")), true, "", nil},
+		"or nil":                     {deny("a").Or(nil), true, "", []string{"a"}},
+		"and":                        {allow("a").And(deny("b")), false, "b", []string{"a", "b"}},
+		"and nil receiver":           {Check[int](nil).And(allow("a")), true, "", []string{"a"}},
+	}
+	for name, tt := range tests {
+		calls = nil
+		got := tt.check.Run(1)
+		if got.Allowed != tt.allowed || got.Reason != tt.reason {
+			t.Errorf("%s: expected allowed %v with reason %q, got %v with %q", name, tt.allowed, tt.reason, got.Allowed, got.Reason)
+		}
+		if len(calls) != len(tt.calls) {
+			t.Errorf("%s: expected calls %q, got %q", name, tt.calls, calls)
+			continue
+		}
+		for i := range calls {
+			if calls[i] != tt.calls[i] {
+				t.Errorf("%s: expected calls %q, got %q", name, tt.calls, calls)
+				break
+			}
+		}
+	}
+}
+
+// 全てのディスパッチャが許可した時だけハンダラを実行することを確認する
+func TestCheckDispatchers(t *testing.T) {
+	tests := map[string]struct {
+		allowed bool
+		want    int
+	}{
+		"allowed": {true, 4},
+		"denied":  {false, 0},
+	}
+	for name, tt := range tests {
+		h := New(log.Default())
+		var called int
+		h.AddMessage(Message{
+			Check:   CheckIf(func(*events.GuildMessageCreate) bool { return tt.allowed }, ""),
+			Handler: func(*events.GuildMessageCreate) error { called++; return nil },
+		})
+		h.AddEvent(Event{
+			Check:   CheckIf(func(bot.Event) bool { return tt.allowed }, ""),
+			Handler: func(bot.Event) error { called++; return nil },
+		})
+		h.MessageReactionAdd.Add(Generics[events.GuildMessageReactionAdd]{
+			Check:   CheckIf(func(*events.GuildMessageReactionAdd) bool { return tt.allowed }, ""),
+			Handler: func(*events.GuildMessageReactionAdd) error { called++; return nil },
+		})
+		h.OnEvent(newGuildMessageCreate(1))
+		h.OnEvent(&events.GuildMessageReactionAdd{})
+		if called != tt.want {
+			t.Errorf("%s: expected %d handlers to run, got %d", name, tt.want, called)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"slices"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-lib/v2/permissions"
+)
+
+// 組み込みのチェックが拒否した時のエラーメッセージの翻訳キー
+const (
+	CheckMissingPermissionsMessage    = "check_missing_permissions"
+	CheckBotMissingPermissionsMessage = "check_bot_missing_permissions"
+	CheckGuildOnlyMessage             = "check_guild_only"
+	CheckDMOnlyMessage                = "check_dm_only"
+	CheckDevOnlyMessage               = "check_dev_only"
+	CheckNSFWOnlyMessage              = "check_nsfw_only"
+)
+
+// 組み込みのチェックに渡せるインタラクションのイベント
+type CheckEvent interface {
+	Client() bot.Client
+	User() discord.User
+	Member() *discord.ResolvedMember
+	GuildID() *snowflake.ID
+	ChannelID() snowflake.ID
+	AppPermissions() *discord.Permissions
+}
+
+type checkConfig struct {
+	key    string
+	silent bool
+}
+
+// 組み込みのチェックの設定
+type CheckOption func(config *checkConfig)
+
+// 拒否した時のエラーメッセージの翻訳キーを変更する
+func DenyWith(key string) CheckOption {
+	return func(config *checkConfig) {
+		config.key = key
+	}
+}
+
+// 拒否した時にエラーメッセージを応答しない
+func DenySilently() CheckOption {
+	return func(config *checkConfig) {
+		config.silent = true
+	}
+}
+
+// 条件を満たさない場合に翻訳キーkeyで拒否するチェックを作成する
+// allowは拒否した時の翻訳のテンプレートデータも返す
+func newCheck[T CheckEvent](key string, opts []CheckOption, allow func(event T) (bool, any)) Check[T] {
+	config := &checkConfig{key: key}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return func(event T) CheckResult {
+		ok, data := allow(event)
+		if ok {
+			return Allow()
+		}
+		if config.silent {
+			return Deny("")
+		}
+		return Deny(config.key, data)
+	}
+}
+
+// メンバーがチャンネルで権限を持っているか
+// DMでは拒否する
+func HasPermissions[T CheckEvent](perms discord.Permissions, opts ...CheckOption) Check[T] {
+	return newCheck(CheckMissingPermissionsMessage, opts, func(event T) (bool, any) {
+		member := event.Member()
+		if member == nil {
+			return false, map[string]any{"Permissions": perms.String()}
+		}
+		if member.Permissions.Has(discord.PermissionAdministrator) || member.Permissions.Has(perms) {
+			return true, nil
+		}
+		return false, map[string]any{"Permissions": member.Permissions.Missing(perms).String()}
+	})
+}
+
+// ボットがチャンネルで権限を持っているか
+func BotHasPermissions[T CheckEvent](perms discord.Permissions, opts ...CheckOption) Check[T] {
+	return newCheck(CheckBotMissingPermissionsMessage, opts, func(event T) (bool, any) {
+		appPermissions := event.AppPermissions()
+		if appPermissions == nil {
+			return false, map[string]any{"Permissions": perms.String()}
+		}
+		if appPermissions.Has(discord.PermissionAdministrator) || appPermissions.Has(perms) {
+			return true, nil
+		}
+		return false, map[string]any{"Permissions": appPermissions.Missing(perms).String()}
+	})
+}
+
+// ユーザーが一覧に含まれるか
+func AllowUsers[T CheckEvent](userIDs []snowflake.ID, opts ...CheckOption) Check[T] {
+	return newCheck(PermissionDeniedMessage, opts, func(event T) (bool, any) {
+		return slices.Contains(userIDs, event.User().ID), nil
+	})
+}
+
+// メンバーが一覧のロールのいずれかを持っているか
+// DMでは拒否する
+func AllowRoles[T CheckEvent](roleIDs []snowflake.ID, opts ...CheckOption) Check[T] {
+	return newCheck(PermissionDeniedMessage, opts, func(event T) (bool, any) {
+		member := event.Member()
+		if member == nil {
+			return false, nil
+		}
+		for _, roleID := range member.RoleIDs {
+			if slices.Contains(roleIDs, roleID) {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+}
+
+// ユーザーがボットの開発者か
+// devUserIDsには設定のDevUserIDsを渡す
+func DevOnly[T CheckEvent](devUserIDs []snowflake.ID, opts ...CheckOption) Check[T] {
+	return newCheck(CheckDevOnlyMessage, opts, func(event T) (bool, any) {
+		return slices.Contains(devUserIDs, event.User().ID), nil
+	})
+}
+
+// ギルドの中か
+func GuildOnly[T CheckEvent](opts ...CheckOption) Check[T] {
+	return newCheck(CheckGuildOnlyMessage, opts, func(event T) (bool, any) {
+		return event.GuildID() != nil, nil
+	})
+}
+
+// DMの中か
+func DMOnly[T CheckEvent](opts ...CheckOption) Check[T] {
+	return newCheck(CheckDMOnlyMessage, opts, func(event T) (bool, any) {
+		return event.GuildID() == nil, nil
+	})
+}
+
+// 年齢制限のあるチャンネルか
+// チャンネルはキャッシュから取得し、見つからない場合は拒否する
+func NSFWOnly[T CheckEvent](opts ...CheckOption) Check[T] {
+	return newCheck(CheckNSFWOnlyMessage, opts, func(event T) (bool, any) {
+		channel, ok := event.Client().Caches().Channel(event.ChannelID())
+		if !ok {
+			return false, nil
+		}
+		messageChannel, ok := channel.(discord.GuildMessageChannel)
+		return ok && messageChannel.NSFW(), nil
+	})
+}
+
+// ユーザーが権限ツリーのノードを持っているか
+// permsはイベントのユーザーの権限を返す
+func HasNode[T CheckEvent](node string, perms func(event T) permissions.Permission, opts ...CheckOption) Check[T] {
+	return newCheck(PermissionDeniedMessage, opts, func(event T) (bool, any) {
+		return perms(event).Has(node), map[string]any{"Node": node}
+	})
+}
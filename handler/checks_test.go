@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-lib/v2/permissions"
+)
+
+type testCheckEvent struct {
+	userID         snowflake.ID
+	member         *discord.ResolvedMember
+	guildID        *snowflake.ID
+	appPermissions *discord.Permissions
+}
+
+func (*testCheckEvent) Client() bot.Client                     { return nil }
+func (e *testCheckEvent) User() discord.User                   { return discord.User{ID: e.userID} }
+func (e *testCheckEvent) Member() *discord.ResolvedMember      { return e.member }
+func (e *testCheckEvent) GuildID() *snowflake.ID               { return e.guildID }
+func (*testCheckEvent) ChannelID() snowflake.ID                { return 0 }
+func (e *testCheckEvent) AppPermissions() *discord.Permissions { return e.appPermissions }
+
+func TestChecks(t *testing.T) {
+	guildID := snowflake.ID(1)
+	memberWith := func(perms discord.Permissions, roleIDs ...snowflake.ID) *discord.ResolvedMember {
+		return &discord.ResolvedMember{Member: discord.Member{RoleIDs: roleIDs}, Permissions: perms}
+	}
+	perms := func(p discord.Permissions) *discord.Permissions { return &p }
+	nodes := func(*testCheckEvent) permissions.Permission { return permissions.New().Add("role.manage") }
+
+	tests := map[string]struct {
+		check   Check[*testCheckEvent]
+		event   testCheckEvent
+		allowed bool
+		reason  string
+	}{
+		"permissions":                {HasPermissions[*testCheckEvent](discord.PermissionManageGuild), testCheckEvent{member: memberWith(discord.PermissionManageGuild | discord.PermissionSendMessages)}, true, ""},
+		"permissions missing":        {HasPermissions[*testCheckEvent](discord.PermissionManageGuild | discord.PermissionBanMembers), testCheckEvent{member: memberWith(discord.PermissionManageGuild)}, false, CheckMissingPermissionsMessage},
+		"permissions administrator":  {HasPermissions[*testCheckEvent](discord.PermissionBanMembers), testCheckEvent{member: memberWith(discord.PermissionAdministrator)}, true, ""},
+		"permissions dm":             {HasPermissions[*testCheckEvent](discord.PermissionsNone), testCheckEvent{}, false, CheckMissingPermissionsMessage},
+		"bot permissions":            {BotHasPermissions[*testCheckEvent](discord.PermissionSendMessages), testCheckEvent{appPermissions: perms(discord.PermissionSendMessages)}, true, ""},
+		"bot permissions missing":    {BotHasPermissions[*testCheckEvent](discord.PermissionBanMembers), testCheckEvent{appPermissions: perms(discord.PermissionSendMessages)}, false, CheckBotMissingPermissionsMessage},
+		"users":                      {AllowUsers[*testCheckEvent]([]snowflake.ID{2, 3}), testCheckEvent{userID: 3}, true, ""},
+		"users denied":               {AllowUsers[*testCheckEvent]([]snowflake.ID{2, 3}), testCheckEvent{userID: 4}, false, PermissionDeniedMessage},
+		"roles":                      {AllowRoles[*testCheckEvent]([]snowflake.ID{10}), testCheckEvent{member: memberWith(0, 9, 10)}, true, ""},
+		"roles denied":               {AllowRoles[*testCheckEvent]([]snowflake.ID{10}), testCheckEvent{member: memberWith(0, 9)}, false, PermissionDeniedMessage},
+		"dev":                        {DevOnly[*testCheckEvent]([]snowflake.ID{5}), testCheckEvent{userID: 5}, true, ""},
+		"guild only":                 {GuildOnly[*testCheckEvent](), testCheckEvent{guildID: &guildID}, true, ""},
+		"guild only in dm":           {GuildOnly[*testCheckEvent](), testCheckEvent{}, false, CheckGuildOnlyMessage},
+		"dm only":                    {DMOnly[*testCheckEvent](), testCheckEvent{}, true, ""},
+		"dm only in guild":           {DMOnly[*testCheckEvent](), testCheckEvent{guildID: &guildID}, false, CheckDMOnlyMessage},
+		"node":                       {HasNode[*testCheckEvent]("role.manage", nodes), testCheckEvent{}, true, ""},
+		"node denied":                {HasNode[*testCheckEvent]("role.delete", nodes), testCheckEvent{}, false, PermissionDeniedMessage},
+		"denied silently":            {AllowUsers[*testCheckEvent](nil, DenySilently()), testCheckEvent{}, false, ""},
+		"denied with a custom reply": {AllowUsers[*testCheckEvent](nil, DenyWith("custom")), testCheckEvent{}, false, "custom"},
+	}
+	for name, tt := range tests {
+		got := tt.check.Run(&tt.event)
+		if got.Allowed != tt.allowed || got.Reason != tt.reason {
+			t.Errorf("%s: expected allowed %v with reason %q, got %v with %q", name, tt.allowed, tt.reason, got.Allowed, got.Reason)
+		}
+	}
+}
@@ -1,18 +1,35 @@
 package handler
 
 import (
-	"time"
+	"context"
+	"fmt"
+	"slices"
 
+	"github.com/disgoorg/snowflake/v2"
 	"github.com/sabafly/sabafly-disgo/discord"
 	"github.com/sabafly/sabafly-disgo/events"
-	"github.com/sabafly/sabafly-disgo/rest"
 )
 
 type (
 	CommandHandler      func(event *events.ApplicationCommandInteractionCreate) error
 	AutocompleteHandler func(event *events.AutocompleteInteractionCreate) error
+
+	CommandContextHandler      func(ctx context.Context, event *events.ApplicationCommandInteractionCreate) error
+	AutocompleteContextHandler func(ctx context.Context, event *events.AutocompleteInteractionCreate) error
 )
 
+func (f CommandHandler) WithContext() CommandContextHandler {
+	return func(_ context.Context, event *events.ApplicationCommandInteractionCreate) error {
+		return f(event)
+	}
+}
+
+func (f AutocompleteHandler) WithContext() AutocompleteContextHandler {
+	return func(_ context.Context, event *events.AutocompleteInteractionCreate) error {
+		return f(event)
+	}
+}
+
 type Command struct {
 	Create               discord.ApplicationCommandCreate
 	Check                Check[*events.ApplicationCommandInteractionCreate]
@@ -22,92 +39,126 @@ type Command struct {
 	CommandHandlers      map[string]CommandHandler
 	AutocompleteHandlers map[string]AutocompleteHandler
 	Ephemeral            map[string]bool
+	Middlewares          []Middleware
+	// パスごとのクールダウン
+	Cooldowns map[string]Cooldown
+	// ハンダラが返したエラーを処理する
+	OnError ErrorHandler
+
+	CommandContextHandlers      map[string]CommandContextHandler
+	AutocompleteContextHandlers map[string]AutocompleteContextHandler
 
 	DevOnly bool
+	// 指定した場合はグローバルではなくこれらのギルドにだけ登録する
+	GuildIDs []snowflake.ID
+	// 指定した場合はtrueを返すギルドにだけ登録する
+	// 結果が変わった場合はSyncGuildで再同期する
+	GuildFilter func(guildID snowflake.ID) bool
 }
 
-func (h *Handler) handleCommand(event *events.ApplicationCommandInteractionCreate) {
-	if h.IsLogEvent {
-		switch d := event.Data.(type) {
-		case discord.SlashCommandInteractionData:
-			h.Logger.Infof("%s(%s) used %s command type %d", event.User().Tag(), event.User().ID, d.CommandPath(), d.Type())
-		case discord.UserCommandInteractionData:
-			h.Logger.Infof("%s(%s) used %s command target %s type %d",
-				event.User().Tag(), event.User().ID, d.CommandName(), d.TargetID(), d.Type(),
-			)
-		case discord.MessageCommandInteractionData:
-			h.Logger.Infof("%s(%s) used %s command target %s type %d",
-				event.User().Tag(), event.User().ID, d.CommandName(), d.TargetMessage().JumpURL(), d.Type(),
-			)
-		default:
-			h.Logger.Infof("%s(%s) used %s command type %d", event.User().Tag(), event.User().ID, d.CommandName(), d.Type())
-		}
+// 登録するギルドが限定されているかどうか
+func (c Command) guildScoped() bool {
+	return len(c.GuildIDs) != 0 || c.GuildFilter != nil
+}
+
+func (c Command) enabledIn(guildID snowflake.ID) bool {
+	if slices.Contains(c.GuildIDs, guildID) {
+		return true
+	}
+	return c.GuildFilter != nil && c.GuildFilter(guildID)
+}
+
+func (c Command) commandHandler(path string) (CommandContextHandler, bool) {
+	if handler, ok := c.CommandContextHandlers[path]; ok {
+		return handler, true
+	}
+	if handler, ok := c.CommandHandlers[path]; ok {
+		return handler.WithContext(), true
+	}
+	return nil, false
+}
+
+func (c Command) autocompleteHandler(path string) (AutocompleteContextHandler, bool) {
+	if handler, ok := c.AutocompleteContextHandlers[path]; ok {
+		return handler, true
+	}
+	if handler, ok := c.AutocompleteHandlers[path]; ok {
+		return handler.WithContext(), true
 	}
+	return nil, false
+}
+
+func (h *Handler) handleCommand(event *events.ApplicationCommandInteractionCreate) {
+	e := *event
+	tracker := h.newAckTracker(event.Client(), event, event.Respond)
+	e.Respond = tracker.respond
+	event = &e
+
 	name := event.Data.CommandName()
 	h.Logger.Debugf("command created %s", name)
-	cmd, ok := h.Commands[name]
-	if !ok || cmd.CommandHandlers == nil {
+	cmd, ok := h.command(registeredCommandKey(event.Data.Type(), name))
+	if !ok || (cmd.CommandHandlers == nil && cmd.CommandContextHandlers == nil) {
 		h.Logger.Errorf("No command or handler found for \"%s\"", name)
 	}
 
-	if cmd.Check != nil && !cmd.Check(event) {
-		return
-	}
-
 	var path string
 	if d, ok := event.Data.(discord.SlashCommandInteractionData); ok {
 		path = buildCommandPath(d.SubCommandName, d.SubCommandGroupName)
 	}
 
-	if check, ok := cmd.Checks[path]; ok && !check(event) {
+	if !checkInteraction(h, event, cmd.Check, cmd.Checks[path]) {
 		return
 	}
 
-	handler, ok := cmd.CommandHandlers[path]
+	handler, ok := cmd.commandHandler(path)
 	if !ok {
 		h.Logger.Warnf("No handler for command \"%s\" with path \"%s\" found", name, path)
 		return
 	}
 
-	defer deferUpdateInteraction(event, cmd.Ephemeral != nil && cmd.Ephemeral[path])
-	if err := handler(event); err != nil {
-		h.Logger.Errorf("Failed to handle command \"%s\" with path \"%s\": %s", name, path, err)
+	if !h.takeCooldown("command", name, path, cmd.Cooldowns, event) {
+		return
 	}
-}
 
-type deferCreateMessage interface {
-	DeferCreateMessage(ephemeral bool, opts ...rest.RequestOpt) error
-}
-
-func deferUpdateInteraction(event deferCreateMessage, ephemeral bool) {
-	time.Sleep(time.Millisecond * 2500)
-	_ = event.DeferCreateMessage(ephemeral)
+	ctx, cancel := h.interactionContext(event)
+	defer cancel()
+	ctx = withAckTracker(ctx, tracker)
+	tracker.autoDefer(h.autoDeferTimeout(), discord.InteractionResponseTypeDeferredCreateMessage, deferCreateMessageData(cmd.Ephemeral != nil && cmd.Ephemeral[path]))
+	run := chain(typed(event, handler), h.middlewares(), cmd.Middlewares)
+	source := fmt.Sprintf("command \"%s\" with path \"%s\"", name, path)
+	if err := h.invoke(source, event, func() error { return run(ctx, event) }); err != nil {
+		h.handleError(ctx, event, event.Locale(), tracker, cmd.OnError, source, err)
+	}
 }
 
 func (h *Handler) handleAutocomplete(event *events.AutocompleteInteractionCreate) {
 	name := event.Data.CommandName
-	cmd, ok := h.Commands[name]
-	if !ok || cmd.AutocompleteHandlers == nil {
+	cmd, ok := h.command(name)
+	if !ok || (cmd.AutocompleteHandlers == nil && cmd.AutocompleteContextHandlers == nil) {
 		h.Logger.Errorf("No autocomplete or handler found for \"%s\"", name)
 	}
 
-	if cmd.AutocompleteCheck != nil && !cmd.AutocompleteCheck(event) {
-		return
-	}
-
 	path := buildCommandPath(event.Data.SubCommandName, event.Data.SubCommandGroupName)
 
-	if check, ok := cmd.AutocompleteChecks[path]; ok && !check(event) {
+	// オートコンプリートはメッセージで応答できないので候補を空にする
+	if !CheckAllOf(cmd.AutocompleteCheck, cmd.AutocompleteChecks[path]).Run(event).Allowed {
+		if err := event.Result(nil); err != nil {
+			h.Logger.Errorf("Failed to respond denied autocomplete \"%s\": %s", name, err)
+		}
 		return
 	}
 
-	handler, ok := cmd.AutocompleteHandlers[path]
+	handler, ok := cmd.autocompleteHandler(path)
 	if !ok {
 		h.Logger.Warnf("No autocomplete handler for autocomplete \"%s\" with path \"%s\" found", name, path)
 		return
 	}
 
-	if err := handler(event); err != nil {
+	ctx, cancel := h.interactionContext(event)
+	defer cancel()
+	run := chain(typed(event, handler), h.middlewares(), cmd.Middlewares)
+	source := fmt.Sprintf("autocomplete \"%s\" with path \"%s\"", name, path)
+	if err := h.invoke(source, event, func() error { return run(ctx, event) }); err != nil {
 		h.Logger.Errorf("Failed to handle autocomplete for autocomplete \"%s\" with path \"%s\": %s", name, path, err)
 	}
 }
@@ -1,12 +1,25 @@
 package handler
 
 import (
-	"strings"
+	"context"
+	"fmt"
 
+	"github.com/sabafly/sabafly-lib/v2/handler/customid"
+
+	"github.com/sabafly/sabafly-disgo/discord"
 	"github.com/sabafly/sabafly-disgo/events"
 )
 
-type ComponentHandler func(event *events.ComponentInteractionCreate) error
+type (
+	ComponentHandler        func(event *events.ComponentInteractionCreate) error
+	ComponentContextHandler func(ctx context.Context, event *events.ComponentInteractionCreate) error
+)
+
+func (f ComponentHandler) WithContext() ComponentContextHandler {
+	return func(_ context.Context, event *events.ComponentInteractionCreate) error {
+		return f(event)
+	}
+}
 
 type Component struct {
 	Name      string
@@ -14,38 +27,59 @@ type Component struct {
 	Checks    map[string]Check[*events.ComponentInteractionCreate]
 	Handler   map[string]ComponentHandler
 	Ephemeral map[string]bool
+	// trueの場合は遅延応答にDeferUpdateMessageを使う
+	DeferUpdate map[string]bool
+	Middlewares []Middleware
+	// サブハンダラごとのクールダウン
+	Cooldowns map[string]Cooldown
+	// ハンダラが返したエラーを処理する
+	OnError ErrorHandler
+
+	ContextHandler map[string]ComponentContextHandler
 }
 
-func (h *Handler) handleComponent(event *events.ComponentInteractionCreate) {
-	if h.IsLogEvent {
-		h.Logger.Infof("%s(%s) used %s component", event.Member().User.Tag(), event.Member().User.ID, event.Data.CustomID())
+func (c Component) handler(name string) (ComponentContextHandler, bool) {
+	if handler, ok := c.ContextHandler[name]; ok {
+		return handler, true
+	}
+	if handler, ok := c.Handler[name]; ok {
+		return handler.WithContext(), true
 	}
+	return nil, false
+}
+
+func (h *Handler) handleComponent(event *events.ComponentInteractionCreate) {
+	e := *event
+	tracker := h.newAckTracker(event.Client(), event, event.Respond)
+	e.Respond = tracker.respond
+	event = &e
+
 	customID := event.Data.CustomID()
 	h.Logger.Debugf("コンポーネントインタラクション呼び出し %s", customID)
-	if !strings.HasPrefix(customID, "handler:") {
+	if !customid.IsHandlerID(customID) {
 		return
 	}
-
-	var subName string
-	if strings.Count(customID, ":") >= 2 {
-		subName = strings.Split(customID, ":")[2]
+	id, err := customid.Decode(customID, h.CustomIDKey)
+	if err != nil {
+		h.Logger.Warnf("不正なカスタムID %s: %s", customID, err)
+		if err := event.DeferUpdateMessage(); err != nil {
+			h.Logger.Errorf("Failed to handle invalid custom id interaction for \"%s\" : %s", customID, err)
+		}
+		return
 	}
+	subName := id.Sub
 
-	componentName := strings.Split(customID, ":")[1]
-	component, ok := h.Components[componentName]
-	if !ok || component.Handler == nil {
+	componentName := id.Name
+	component, ok := h.component(componentName)
+	if !ok || (component.Handler == nil && component.ContextHandler == nil) {
 		h.Logger.Errorf("No component handler for \"%s\" found", componentName)
 	}
 
-	if component.Check != nil && !component.Check(event) {
-		return
-	}
-
-	if check, ok := component.Checks[subName]; ok && !check(event) {
+	if !checkInteraction(h, event, component.Check, component.Checks[subName]) {
 		return
 	}
 
-	handler, ok := component.Handler[subName]
+	handler, ok := component.handler(subName)
 	if !ok {
 		h.Logger.Debugf("不明なハンダラ %s", subName)
 		err := event.DeferUpdateMessage()
@@ -55,8 +89,25 @@ func (h *Handler) handleComponent(event *events.ComponentInteractionCreate) {
 		return
 	}
 
-	defer deferUpdateInteraction(event, component.Ephemeral != nil && component.Ephemeral[subName])
-	if err := handler(event); err != nil {
-		h.Logger.Errorf("Failed to handle component interaction for \"%s\" : %s", componentName, err)
+	if !h.takeCooldown("component", componentName, subName, component.Cooldowns, event) {
+		return
+	}
+
+	ctx, cancel := h.interactionContext(event)
+	defer cancel()
+	ctx = withAckTracker(ctx, tracker)
+	ctx = withCustomID(ctx, id)
+	if ctx, ok = h.loadState(ctx, id, event); !ok {
+		return
+	}
+	if component.DeferUpdate != nil && component.DeferUpdate[subName] {
+		tracker.autoDefer(h.autoDeferTimeout(), discord.InteractionResponseTypeDeferredUpdateMessage, nil)
+	} else {
+		tracker.autoDefer(h.autoDeferTimeout(), discord.InteractionResponseTypeDeferredCreateMessage, deferCreateMessageData(component.Ephemeral != nil && component.Ephemeral[subName]))
+	}
+	run := chain(typed(event, handler), h.middlewares(), component.Middlewares)
+	source := fmt.Sprintf("component \"%s\" with handler \"%s\"", componentName, subName)
+	if err := h.invoke(source, event, func() error { return run(ctx, event) }); err != nil {
+		h.handleError(ctx, event, event.Locale(), tracker, component.OnError, source, err)
 	}
 }
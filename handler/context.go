@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// インタラクションに最初の応答を返すまでの猶予
+	InteractionResponseTimeout = 3 * time.Second
+	// インタラクショントークンの有効期限
+	InteractionTokenTimeout = 15 * time.Minute
+)
+
+type responseDeadlineKey struct{}
+
+type createdAt interface {
+	CreatedAt() time.Time
+}
+
+func (h *Handler) baseContext() context.Context {
+	if h.ctx == nil {
+		return context.Background()
+	}
+	return h.ctx
+}
+
+// インタラクション用のコンテキストを作成する
+// トークンの有効期限を期限とし、最初の応答の期限を値として持つ
+func (h *Handler) interactionContext(interaction createdAt) (context.Context, context.CancelFunc) {
+	created := interaction.CreatedAt()
+	ctx := context.WithValue(h.baseContext(), responseDeadlineKey{}, created.Add(InteractionResponseTimeout))
+	return context.WithDeadline(ctx, created.Add(InteractionTokenTimeout))
+}
+
+// インタラクション以外のイベント用のコンテキストを作成する
+func (h *Handler) eventContext() (context.Context, context.CancelFunc) {
+	if h.EventTimeout > 0 {
+		return context.WithTimeout(h.baseContext(), h.EventTimeout)
+	}
+	return context.WithCancel(h.baseContext())
+}
+
+// インタラクションに最初の応答を返さなければならない時刻を返す
+func ResponseDeadline(ctx context.Context) (time.Time, bool) {
+	deadline, ok := ctx.Value(responseDeadlineKey{}).(time.Time)
+	return deadline, ok
+}
+
+// 新しいイベントの受け付けを止め、処理中のイベントが終わるのを待ってからハンダラのコンテキストをキャンセルする
+// 先にctxが終了した場合はその時点でキャンセルし、ctxのエラーを返す
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.dispatchMu.Lock()
+	h.shutdown = true
+	d := h.dispatcher
+	h.dispatchMu.Unlock()
+	if h.cancel != nil {
+		defer h.cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.inFlight.Wait()
+		if d != nil {
+			d.close()
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/disgoorg/json"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+	"github.com/sabafly/sabafly-lib/v2/translate"
+)
+
+type (
+	// memberはギルドの外で実行された場合や対象がメンバーでない場合にnilになる
+	UserCommandHandler    func(ctx context.Context, event *events.ApplicationCommandInteractionCreate, user discord.User, member *discord.ResolvedMember) error
+	MessageCommandHandler func(ctx context.Context, event *events.ApplicationCommandInteractionCreate, message discord.Message) error
+)
+
+// ユーザーのコンテキストメニューから実行するコマンド
+type UserCommand struct {
+	Name              string
+	NameLocalizations map[discord.Locale]string
+	// 指定した場合は キー_name の翻訳を名前のローカライズに使う
+	Localize                 string
+	DefaultMemberPermissions *json.Nullable[discord.Permissions]
+	DMPermission             *bool
+	NSFW                     bool
+
+	Check       Check[*events.ApplicationCommandInteractionCreate]
+	Ephemeral   bool
+	Middlewares []Middleware
+	Handler     UserCommandHandler
+	OnError     ErrorHandler
+
+	DevOnly bool
+}
+
+// コマンドの作成データとハンダラを生成する
+func (c UserCommand) Command() Command {
+	handler := c.Handler
+	return Command{
+		Create: discord.UserCommandCreate{
+			Name:                     c.Name,
+			NameLocalizations:        contextMenuLocalizations(c.Localize, c.NameLocalizations),
+			DefaultMemberPermissions: c.DefaultMemberPermissions,
+			DMPermission:             c.DMPermission,
+			NSFW:                     c.NSFW,
+		},
+		Check:       c.Check,
+		Ephemeral:   map[string]bool{"": c.Ephemeral},
+		Middlewares: c.Middlewares,
+		OnError:     c.OnError,
+		CommandContextHandlers: map[string]CommandContextHandler{
+			"": func(ctx context.Context, event *events.ApplicationCommandInteractionCreate) error {
+				data, ok := event.Data.(discord.UserCommandInteractionData)
+				if !ok {
+					return fmt.Errorf("unexpected command data %T", event.Data)
+				}
+				// 対象がギルドのメンバーでない場合は解決済みのメンバーに含まれない
+				var member *discord.ResolvedMember
+				if m, ok := data.Resolved.Members[data.TargetID()]; ok {
+					member = &m
+				}
+				if handler == nil {
+					return nil
+				}
+				return handler(ctx, event, data.TargetUser(), member)
+			},
+		},
+		DevOnly: c.DevOnly,
+	}
+}
+
+// メッセージのコンテキストメニューから実行するコマンド
+type MessageCommand struct {
+	Name              string
+	NameLocalizations map[discord.Locale]string
+	// 指定した場合は キー_name の翻訳を名前のローカライズに使う
+	Localize                 string
+	DefaultMemberPermissions *json.Nullable[discord.Permissions]
+	DMPermission             *bool
+	NSFW                     bool
+
+	Check       Check[*events.ApplicationCommandInteractionCreate]
+	Ephemeral   bool
+	Middlewares []Middleware
+	Handler     MessageCommandHandler
+	OnError     ErrorHandler
+
+	DevOnly bool
+}
+
+// コマンドの作成データとハンダラを生成する
+func (c MessageCommand) Command() Command {
+	handler := c.Handler
+	return Command{
+		Create: discord.MessageCommandCreate{
+			Name:                     c.Name,
+			NameLocalizations:        contextMenuLocalizations(c.Localize, c.NameLocalizations),
+			DefaultMemberPermissions: c.DefaultMemberPermissions,
+			DMPermission:             c.DMPermission,
+			NSFW:                     c.NSFW,
+		},
+		Check:       c.Check,
+		Ephemeral:   map[string]bool{"": c.Ephemeral},
+		Middlewares: c.Middlewares,
+		OnError:     c.OnError,
+		CommandContextHandlers: map[string]CommandContextHandler{
+			"": func(ctx context.Context, event *events.ApplicationCommandInteractionCreate) error {
+				data, ok := event.Data.(discord.MessageCommandInteractionData)
+				if !ok {
+					return fmt.Errorf("unexpected command data %T", event.Data)
+				}
+				if handler == nil {
+					return nil
+				}
+				return handler(ctx, event, data.TargetMessage())
+			},
+		},
+		DevOnly: c.DevOnly,
+	}
+}
+
+// コンテキストメニューの名前は空白を含められるので置き換えない
+func contextMenuLocalizations(key string, localizations map[discord.Locale]string) map[discord.Locale]string {
+	if key == "" {
+		return localizations
+	}
+	return translate.MessageMap(key+"_name", false)
+}
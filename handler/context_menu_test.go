@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/disgoorg/log"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+)
+
+func TestContextMenuCommands(t *testing.T) {
+	h := New(log.Default())
+	h.AddCommands(
+		Command{Create: discord.SlashCommandCreate{Name: "info"}},
+		UserCommand{Name: "info"}.Command(),
+		MessageCommand{Name: "info"}.Command(),
+	)
+	if len(h.Commands) != 3 {
+		t.Fatalf("expected commands of each type to coexist, got %d", len(h.Commands))
+	}
+	for _, typ := range []discord.ApplicationCommandType{discord.ApplicationCommandTypeSlash, discord.ApplicationCommandTypeUser, discord.ApplicationCommandTypeMessage} {
+		cmd, ok := h.command(registeredCommandKey(typ, "info"))
+		if !ok || cmd.Create.Type() != typ {
+			t.Errorf("expected %d command, got %v %v", typ, cmd.Create, ok)
+		}
+		if _, ok := cmd.commandHandler(""); typ != discord.ApplicationCommandTypeSlash && !ok {
+			t.Errorf("expected handler for %d command", typ)
+		}
+	}
+
+	h.RemoveUserCommand("info")
+	if _, ok := h.command(registeredCommandKey(discord.ApplicationCommandTypeUser, "info")); ok {
+		t.Error("expected user command to be removed")
+	}
+	if _, ok := h.command("info"); !ok {
+		t.Error("expected slash command to remain")
+	}
+
+	h.RemoveCommand("info")
+	if len(h.Commands) != 0 {
+		t.Errorf("expected commands of every type to be removed, got %d", len(h.Commands))
+	}
+}
+
+func TestUserCommandMember(t *testing.T) {
+	var got *discord.ResolvedMember
+	handler, _ := UserCommand{
+		Name: "info",
+		Handler: func(_ context.Context, _ *events.ApplicationCommandInteractionCreate, _ discord.User, member *discord.ResolvedMember) error {
+			got = member
+			return nil
+		},
+	}.Command().commandHandler("")
+	run := func(members map[snowflake.ID]discord.ResolvedMember) *discord.ResolvedMember {
+		got = nil
+		event := &events.ApplicationCommandInteractionCreate{
+			ApplicationCommandInteraction: discord.ApplicationCommandInteraction{
+				Data: discord.UserCommandInteractionData{Resolved: discord.UserCommandResolved{Members: members}},
+			},
+		}
+		if err := handler(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	// 対象がメンバーでない場合はゼロ値ではなくnilを渡す
+	if member := run(nil); member != nil {
+		t.Errorf("expected nil member, got %v", member)
+	}
+	data := discord.UserCommandInteractionData{}
+	if member := run(map[snowflake.ID]discord.ResolvedMember{data.TargetID(): {}}); member == nil {
+		t.Error("expected resolved member")
+	}
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/log"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+	"github.com/sabafly/sabafly-disgo/rest"
+)
+
+type testCreatedAt time.Time
+
+func (c testCreatedAt) CreatedAt() time.Time { return time.Time(c) }
+
+func TestInteractionContext(t *testing.T) {
+	h := New(log.Default())
+	created := time.Now()
+	ctx, cancel := h.interactionContext(testCreatedAt(created))
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || !deadline.Equal(created.Add(InteractionTokenTimeout)) {
+		t.Errorf("expected token deadline, got %v %v", deadline, ok)
+	}
+	if deadline, ok := ResponseDeadline(ctx); !ok || !deadline.Equal(created.Add(InteractionResponseTimeout)) {
+		t.Errorf("expected response deadline, got %v %v", deadline, ok)
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("expected context to be live, got %v", ctx.Err())
+	}
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() != context.Canceled {
+		t.Errorf("expected context to be canceled on shutdown, got %v", ctx.Err())
+	}
+}
+
+func TestHandlerContext(t *testing.T) {
+	h := New(log.Default())
+	h.ASync = true
+	deadlines := make(chan time.Time, 1)
+	h.AddCommands(Command{
+		Create: discord.SlashCommandCreate{},
+		CommandContextHandlers: map[string]CommandContextHandler{
+			"": func(ctx context.Context, event *events.ApplicationCommandInteractionCreate) error {
+				deadline, _ := ctx.Deadline()
+				deadlines <- deadline
+				if response, ok := ResponseDeadline(ctx); !ok || !response.Equal(event.CreatedAt().Add(InteractionResponseTimeout)) {
+					t.Errorf("expected response deadline, got %v %v", response, ok)
+				}
+				return nil
+			},
+		},
+	})
+	canceled := make(chan error, 1)
+	h.AddMessages(Message{
+		ContextHandler: func(ctx context.Context, _ *events.GuildMessageCreate) error {
+			<-ctx.Done()
+			canceled <- ctx.Err()
+			return nil
+		},
+	})
+
+	event := &events.ApplicationCommandInteractionCreate{
+		GenericEvent:                  events.NewGenericEvent(nil, 0, 0),
+		ApplicationCommandInteraction: discord.ApplicationCommandInteraction{Data: discord.SlashCommandInteractionData{}},
+		Respond: func(discord.InteractionResponseType, discord.InteractionResponseData, ...rest.RequestOpt) error {
+			return nil
+		},
+	}
+	h.OnEvent(event)
+	if deadline := <-deadlines; !deadline.Equal(event.CreatedAt().Add(InteractionTokenTimeout)) {
+		t.Errorf("expected the interaction token deadline, got %v", deadline)
+	}
+
+	// 処理中のハンダラが終わらないまま期限が来るとコンテキストをキャンセルする
+	h.OnEvent(newGuildMessageCreate(1))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := h.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected shutdown to time out, got %v", err)
+	}
+	select {
+	case err := <-canceled:
+		if err != context.Canceled {
+			t.Errorf("expected handler context to be canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected handler context to be canceled on shutdown")
+	}
+}
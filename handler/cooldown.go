@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/discord"
+)
+
+// クールダウン中に返すエラーメッセージの翻訳キー
+// テンプレートではSecondsとRetryAtを使用できる
+const CooldownMessage = "cooldown"
+
+// クールダウンを共有する範囲
+type CooldownScope int
+
+const (
+	// ユーザーごと
+	CooldownScopeUser CooldownScope = iota
+	// ギルドのメンバーごと
+	CooldownScopeMember
+	// ギルドごと
+	CooldownScopeGuild
+	// チャンネルごと
+	CooldownScopeChannel
+)
+
+// トークンバケットによるクールダウン
+// Perの間にRate回まで実行でき、トークンは連続的に補充される
+type Cooldown struct {
+	Scope CooldownScope
+	Rate  int
+	Per   time.Duration
+}
+
+func (c Cooldown) interval() time.Duration {
+	return c.Per / time.Duration(c.Rate)
+}
+
+// クールダウンのバケットを保存するストア
+type CooldownStore interface {
+	// バケットからトークンを取り出す
+	// 取り出せない場合はokがfalseになり、次に取り出せるまでの時間を返す
+	Take(key string, cooldown Cooldown, now time.Time) (retryAfter time.Duration, ok bool)
+}
+
+type cooldownBucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time
+}
+
+type memoryCooldownStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*cooldownBucket
+	lastPrune time.Time
+}
+
+// メモリにバケットを保存するストアを作成する
+func NewMemoryCooldownStore() CooldownStore {
+	return &memoryCooldownStore{buckets: map[string]*cooldownBucket{}}
+}
+
+func (s *memoryCooldownStore) Take(key string, cooldown Cooldown, now time.Time) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(now)
+
+	capacity := float64(cooldown.Rate)
+	interval := cooldown.interval()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &cooldownBucket{tokens: capacity, last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.last))/float64(interval))
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) * float64(interval)), false
+	}
+	b.tokens--
+	b.full = now.Add(time.Duration((capacity - b.tokens) * float64(interval)))
+	return 0, true
+}
+
+// 満杯まで補充されたバケットを削除する
+func (s *memoryCooldownStore) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < time.Minute {
+		return
+	}
+	s.lastPrune = now
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+type cooldownInteraction interface {
+	Responder
+	User() discord.User
+	GuildID() *snowflake.ID
+	ChannelID() snowflake.ID
+}
+
+func cooldownKey(kind, name, path string, cooldown Cooldown, interaction cooldownInteraction) string {
+	key := kind + ":" + name + ":" + path + ":"
+	switch cooldown.Scope {
+	case CooldownScopeMember:
+		if guildID := interaction.GuildID(); guildID != nil {
+			return key + "member:" + guildID.String() + ":" + interaction.User().ID.String()
+		}
+	case CooldownScopeGuild:
+		if guildID := interaction.GuildID(); guildID != nil {
+			return key + "guild:" + guildID.String()
+		}
+	case CooldownScopeChannel:
+		return key + "channel:" + interaction.ChannelID().String()
+	}
+	// DMではユーザーごとに扱う
+	return key + "user:" + interaction.User().ID.String()
+}
+
+// クールダウンを消費する
+// クールダウン中の場合は応答してfalseを返す
+func (h *Handler) takeCooldown(kind, name, path string, cooldowns map[string]Cooldown, interaction cooldownInteraction) bool {
+	cooldown, ok := cooldowns[path]
+	if !ok || cooldown.Rate <= 0 || cooldown.Per <= 0 || h.CooldownStore == nil {
+		return true
+	}
+	now := time.Now()
+	retryAfter, ok := h.CooldownStore.Take(cooldownKey(kind, name, path, cooldown, interaction), cooldown, now)
+	if ok {
+		return true
+	}
+	h.Logger.Debugf("クールダウン中 %s:%s:%s %s", kind, name, path, retryAfter)
+	if err := h.replyErrorMessage(interaction, CooldownMessage, true, map[string]any{
+		"Seconds": int(math.Ceil(retryAfter.Seconds())),
+		"RetryAt": now.Add(retryAfter).Unix(),
+	}); err != nil {
+		h.Logger.Errorf("Failed to reply cooldown for %s \"%s\" with path \"%s\": %s", kind, name, path, err)
+	}
+	return false
+}
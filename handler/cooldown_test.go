@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryCooldownStore(t *testing.T) {
+	store := NewMemoryCooldownStore()
+	cooldown := Cooldown{Rate: 2, Per: 10 * time.Second}
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, ok := store.Take("a", cooldown, now); !ok {
+			t.Fatalf("take %d: expected token", i)
+		}
+	}
+	retryAfter, ok := store.Take("a", cooldown, now)
+	if ok || retryAfter != 5*time.Second {
+		t.Fatalf("expected retry after 5s, got %s %v", retryAfter, ok)
+	}
+	if _, ok := store.Take("b", cooldown, now); !ok {
+		t.Error("expected separate bucket for another key")
+	}
+	if _, ok := store.Take("a", cooldown, now.Add(5*time.Second)); !ok {
+		t.Error("expected token to be refilled")
+	}
+	if _, ok := store.Take("a", cooldown, now.Add(6*time.Second)); ok {
+		t.Error("expected bucket to be empty")
+	}
+}
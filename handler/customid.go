@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/sabafly/sabafly-lib/v2/handler/customid"
+)
+
+type customIDKey struct{}
+
+func withCustomID(ctx context.Context, id *customid.ID) context.Context {
+	return context.WithValue(ctx, customIDKey{}, id)
+}
+
+// ハンダラを呼び出したカスタムIDを返す
+func CustomID(ctx context.Context) (*customid.ID, bool) {
+	id, ok := ctx.Value(customIDKey{}).(*customid.ID)
+	return id, ok
+}
+
+// ハンダラのCustomIDKeyで署名したカスタムIDを返す
+func (h *Handler) EncodeCustomID(b *customid.Builder) (string, error) {
+	return b.Encode(h.CustomIDKey)
+}
+
+// このコンポーネントのサブハンダラを呼び出すカスタムIDを組み立てる
+func (c Component) CustomID(sub string) *customid.Builder {
+	return customid.New(c.Name, sub)
+}
+
+// このモーダルのサブハンダラを呼び出すカスタムIDを組み立てる
+func (m Modal) CustomID(sub string) *customid.Builder {
+	return customid.New(m.Name, sub)
+}
@@ -0,0 +1,298 @@
+package customid
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	// カスタムIDの接頭辞
+	Prefix = "handler"
+	// カスタムIDの最大長
+	MaxLength = 100
+	// 文字列引数の最大長
+	MaxTextLength = 32
+
+	signatureLength = 8
+)
+
+const (
+	typeSnowflake byte = 's'
+	typeInt       byte = 'i'
+	typeUUID      byte = 'u'
+	typeText      byte = 't'
+	typeBool      byte = 'b'
+	typeState     byte = 'S'
+)
+
+var (
+	ErrTooLong          = errors.New("custom id is too long")
+	ErrInvalidFormat    = errors.New("invalid custom id format")
+	ErrInvalidSignature = errors.New("invalid custom id signature")
+	ErrArgument         = errors.New("invalid custom id argument")
+
+	encoding = base64.RawURLEncoding
+)
+
+// カスタムIDを組み立てる
+type Builder struct {
+	name string
+	sub  string
+	buf  bytes.Buffer
+	err  error
+}
+
+func New(name, sub string) *Builder {
+	b := &Builder{name: name, sub: sub}
+	if strings.Contains(name, ":") || strings.Contains(sub, ":") {
+		b.err = fmt.Errorf("%w: name and sub must not contain ':'", ErrInvalidFormat)
+	}
+	return b
+}
+
+func (b *Builder) Snowflake(v snowflake.ID) *Builder {
+	b.buf.WriteByte(typeSnowflake)
+	b.buf.Write(binary.AppendUvarint(nil, uint64(v)))
+	return b
+}
+
+func (b *Builder) Int(v int64) *Builder {
+	b.buf.WriteByte(typeInt)
+	b.buf.Write(binary.AppendVarint(nil, v))
+	return b
+}
+
+func (b *Builder) UUID(v uuid.UUID) *Builder {
+	b.buf.WriteByte(typeUUID)
+	b.buf.Write(v[:])
+	return b
+}
+
+func (b *Builder) Text(v string) *Builder {
+	if len(v) > MaxTextLength {
+		b.err = fmt.Errorf("%w: text %q is longer than %d bytes", ErrArgument, v, MaxTextLength)
+		return b
+	}
+	b.buf.WriteByte(typeText)
+	b.buf.Write(binary.AppendUvarint(nil, uint64(len(v))))
+	b.buf.WriteString(v)
+	return b
+}
+
+// 状態ストアのキーを設定する
+// 状態は引数には含まれず、ID.Stateで取得する
+func (b *Builder) State(key uuid.UUID) *Builder {
+	b.buf.WriteByte(typeState)
+	b.buf.Write(key[:])
+	return b
+}
+
+func (b *Builder) Bool(v bool) *Builder {
+	b.buf.WriteByte(typeBool)
+	if v {
+		b.buf.WriteByte(1)
+	} else {
+		b.buf.WriteByte(0)
+	}
+	return b
+}
+
+// カスタムIDを文字列にする
+// keyがnilでない場合は引数をHMACで署名する
+func (b *Builder) Encode(key []byte) (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+	id := Prefix + ":" + b.name + ":" + b.sub
+	if b.buf.Len() != 0 {
+		payload := encoding.EncodeToString(b.buf.Bytes())
+		id += ":" + payload
+		if key != nil {
+			id += ":" + sign(key, b.name, b.sub, payload)
+		}
+	}
+	if len(id) > MaxLength {
+		return "", fmt.Errorf("%w: %d characters", ErrTooLong, len(id))
+	}
+	return id, nil
+}
+
+func (b *Builder) MustEncode(key []byte) string {
+	id, err := b.Encode(key)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+func sign(key []byte, name, sub, payload string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + ":" + sub + ":" + payload))
+	return encoding.EncodeToString(mac.Sum(nil)[:signatureLength])
+}
+
+// デコードされたカスタムID
+type ID struct {
+	Name string
+	Sub  string
+	// 引数として解釈できなかった従来の形式のカスタムIDの、サブ以降の部分
+	Raw   string
+	args  []any
+	state *uuid.UUID
+}
+
+// 接頭辞がhandlerのカスタムIDかどうか
+func IsHandlerID(s string) bool {
+	return strings.HasPrefix(s, Prefix+":")
+}
+
+// カスタムIDをデコードする
+// keyがnilでない場合、引数は署名が正しいものだけを受け付ける
+// 引数として解釈できない場合は従来の形式として名前とサブだけを読み、残りをRawに入れる
+func Decode(s string, key []byte) (*ID, error) {
+	if !IsHandlerID(s) {
+		return nil, ErrInvalidFormat
+	}
+	parts := strings.SplitN(s, ":", 4)
+	id := &ID{Name: parts[1]}
+	if len(parts) >= 3 {
+		id.Sub = parts[2]
+	}
+	if len(parts) < 4 {
+		return id, nil
+	}
+	id.Raw = parts[3]
+	payload, signature, signed := strings.Cut(id.Raw, ":")
+	if strings.Contains(signature, ":") {
+		return id, nil
+	}
+	buf, err := encoding.DecodeString(payload)
+	if err != nil {
+		return id, nil
+	}
+	decoded := &ID{Name: id.Name, Sub: id.Sub}
+	if err := decoded.decodeArgs(buf); err != nil {
+		return id, nil
+	}
+	if key != nil {
+		if !signed {
+			// 署名のない引数は信用しない
+			return id, nil
+		}
+		if !hmac.Equal([]byte(signature), []byte(sign(key, id.Name, id.Sub, payload))) {
+			return nil, ErrInvalidSignature
+		}
+	}
+	return decoded, nil
+}
+
+func (id *ID) decodeArgs(buf []byte) error {
+	var args []any
+	for len(buf) > 0 {
+		t := buf[0]
+		buf = buf[1:]
+		switch t {
+		case typeSnowflake:
+			v, n := binary.Uvarint(buf)
+			if n <= 0 {
+				return ErrInvalidFormat
+			}
+			args = append(args, snowflake.ID(v))
+			buf = buf[n:]
+		case typeInt:
+			v, n := binary.Varint(buf)
+			if n <= 0 {
+				return ErrInvalidFormat
+			}
+			args = append(args, v)
+			buf = buf[n:]
+		case typeUUID:
+			if len(buf) < 16 {
+				return ErrInvalidFormat
+			}
+			var v uuid.UUID
+			copy(v[:], buf[:16])
+			args = append(args, v)
+			buf = buf[16:]
+		case typeText:
+			l, n := binary.Uvarint(buf)
+			if n <= 0 || uint64(len(buf)-n) < l {
+				return ErrInvalidFormat
+			}
+			args = append(args, string(buf[n:n+int(l)]))
+			buf = buf[n+int(l):]
+		case typeState:
+			if len(buf) < 16 || id.state != nil {
+				return ErrInvalidFormat
+			}
+			var v uuid.UUID
+			copy(v[:], buf[:16])
+			id.state = &v
+			buf = buf[16:]
+		case typeBool:
+			if len(buf) < 1 {
+				return ErrInvalidFormat
+			}
+			args = append(args, buf[0] == 1)
+			buf = buf[1:]
+		default:
+			return fmt.Errorf("%w: unknown argument type %q", ErrInvalidFormat, t)
+		}
+	}
+	id.args = args
+	return nil
+}
+
+// 状態ストアのキーを返す
+func (id *ID) State() (uuid.UUID, bool) {
+	if id.state == nil {
+		return uuid.UUID{}, false
+	}
+	return *id.state, true
+}
+
+// 引数の数
+func (id *ID) Len() int {
+	return len(id.args)
+}
+
+func arg[T any](id *ID, i int) (T, error) {
+	var zero T
+	if i < 0 || i >= len(id.args) {
+		return zero, fmt.Errorf("%w: index %d out of range", ErrArgument, i)
+	}
+	v, ok := id.args[i].(T)
+	if !ok {
+		return zero, fmt.Errorf("%w: argument %d is %T, not %T", ErrArgument, i, id.args[i], zero)
+	}
+	return v, nil
+}
+
+func (id *ID) Snowflake(i int) (snowflake.ID, error) {
+	return arg[snowflake.ID](id, i)
+}
+
+func (id *ID) Int(i int) (int64, error) {
+	return arg[int64](id, i)
+}
+
+func (id *ID) UUID(i int) (uuid.UUID, error) {
+	return arg[uuid.UUID](id, i)
+}
+
+func (id *ID) Text(i int) (string, error) {
+	return arg[string](id, i)
+}
+
+func (id *ID) Bool(i int) (bool, error) {
+	return arg[bool](id, i)
+}
@@ -0,0 +1,110 @@
+package customid
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/google/uuid"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	u := uuid.New()
+	key := []byte("secret")
+	s, err := New("role", "select").Snowflake(1234567890123456789).Int(-42).UUID(u).Text("page").Bool(true).Encode(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) > MaxLength {
+		t.Fatalf("custom id too long: %d", len(s))
+	}
+	if !strings.HasPrefix(s, "handler:role:select:") {
+		t.Errorf("unexpected prefix %q", s)
+	}
+	id, err := Decode(s, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Name != "role" || id.Sub != "select" || id.Len() != 5 {
+		t.Fatalf("unexpected id %+v", id)
+	}
+	if v, err := id.Snowflake(0); err != nil || v != snowflake.ID(1234567890123456789) {
+		t.Errorf("snowflake: %v %v", v, err)
+	}
+	if v, err := id.Int(1); err != nil || v != -42 {
+		t.Errorf("int: %v %v", v, err)
+	}
+	if v, err := id.UUID(2); err != nil || v != u {
+		t.Errorf("uuid: %v %v", v, err)
+	}
+	if v, err := id.Text(3); err != nil || v != "page" {
+		t.Errorf("text: %v %v", v, err)
+	}
+	if v, err := id.Bool(4); err != nil || !v {
+		t.Errorf("bool: %v %v", v, err)
+	}
+	if _, err := id.Int(0); !errors.Is(err, ErrArgument) {
+		t.Errorf("expected type mismatch error, got %v", err)
+	}
+	if _, err := id.Int(5); !errors.Is(err, ErrArgument) {
+		t.Errorf("expected out of range error, got %v", err)
+	}
+}
+
+func TestLegacy(t *testing.T) {
+	s := New("panel", "open").MustEncode([]byte("secret"))
+	if s != "handler:panel:open" {
+		t.Fatalf("unexpected custom id %q", s)
+	}
+	id, err := Decode(s, []byte("secret"))
+	if err != nil || id.Name != "panel" || id.Sub != "open" {
+		t.Fatalf("unexpected id %+v %v", id, err)
+	}
+	if id, err := Decode("handler:panel", nil); err != nil || id.Sub != "" {
+		t.Fatalf("unexpected id %+v %v", id, err)
+	}
+	for _, key := range [][]byte{nil, []byte("secret")} {
+		for s, raw := range map[string]string{
+			"handler:panel:open:123456789":        "123456789",
+			"handler:panel:open:a:b:c":            "a:b:c",
+			"handler:panel:open:role:1:2:3:4:5:6": "role:1:2:3:4:5:6",
+		} {
+			id, err := Decode(s, key)
+			if err != nil || id.Name != "panel" || id.Sub != "open" || id.Raw != raw || id.Len() != 0 {
+				t.Errorf("%s: unexpected id %+v %v", s, id, err)
+			}
+		}
+	}
+}
+
+func TestSignature(t *testing.T) {
+	s := New("role", "select").Int(1).MustEncode([]byte("secret"))
+	if _, err := Decode(s, []byte("other")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected signature error, got %v", err)
+	}
+	// 署名のない引数は従来の形式として読む
+	unsigned := New("role", "select").Int(1).MustEncode(nil)
+	if id, err := Decode(unsigned, []byte("secret")); err != nil || id.Len() != 0 || id.Raw == "" {
+		t.Errorf("unexpected id %+v %v", id, err)
+	}
+	if id, err := Decode(unsigned, nil); err != nil || id.Len() != 1 {
+		t.Errorf("unexpected id %+v %v", id, err)
+	}
+}
+
+func TestTooLong(t *testing.T) {
+	b := New("role", "select")
+	for i := 0; i < 4; i++ {
+		b.Text(strings.Repeat("a", MaxTextLength))
+	}
+	if _, err := b.Encode(nil); !errors.Is(err, ErrTooLong) {
+		t.Errorf("expected too long error, got %v", err)
+	}
+	if _, err := New("a", "b").Text(strings.Repeat("a", MaxTextLength+1)).Encode(nil); !errors.Is(err, ErrArgument) {
+		t.Errorf("expected argument error, got %v", err)
+	}
+	if _, err := New("a:b", "c").Encode(nil); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("expected format error, got %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/events"
+)
+
+// イベントの処理順を保つためのキーを返す
+// 同じキーのイベントは受け取った順に処理される
+type DispatchKeyFunc func(event bot.Event) (key snowflake.ID, ok bool)
+
+type DispatcherConfig struct {
+	// ワーカーの数
+	// 0の場合はCPU数の4倍
+	Workers int
+	// ワーカーごとのキューの長さ
+	// キューが一杯の場合はイベントの受け取りを待たせる
+	// 0の場合は256
+	QueueSize int
+	// nilの場合はDefaultDispatchKey
+	Key DispatchKeyFunc
+}
+
+// 既定のキー
+// メッセージとリアクションはチャンネル、メンバーはギルドごとに順序を保つ
+// インタラクションは応答の期限があるため順序を保たずに順番にワーカーへ振り分ける
+func DefaultDispatchKey(event bot.Event) (snowflake.ID, bool) {
+	switch e := event.(type) {
+	case *events.ApplicationCommandInteractionCreate, *events.AutocompleteInteractionCreate,
+		*events.ComponentInteractionCreate, *events.ModalSubmitInteractionCreate:
+		return 0, false
+	case *events.GuildMessageCreate:
+		return e.ChannelID, true
+	case *events.GuildMessageUpdate:
+		return e.ChannelID, true
+	case *events.GuildMessageDelete:
+		return e.ChannelID, true
+	case *events.GuildMessageReactionAdd:
+		return e.ChannelID, true
+	case *events.GuildMessageReactionRemove:
+		return e.ChannelID, true
+	case *events.GuildMessageReactionRemoveAll:
+		return e.ChannelID, true
+	case *events.GuildMessageReactionRemoveEmoji:
+		return e.ChannelID, true
+	case *events.GuildMemberJoin:
+		return e.GuildID, true
+	case *events.GuildMemberUpdate:
+		return e.GuildID, true
+	case *events.GuildMemberLeave:
+		return e.GuildID, true
+	case interface{ ChannelID() snowflake.ID }:
+		return e.ChannelID(), true
+	}
+	return 0, false
+}
+
+// ワーカープールの状態
+type DispatcherStats struct {
+	Workers   int
+	QueueSize int
+	// キューで待っているイベントの数
+	Queued int64
+	// 処理中のイベントの数
+	InFlight int64
+	// 処理が終わったイベントの数
+	Processed uint64
+	// キューが一杯で受け取りを待たせた回数
+	Blocked uint64
+}
+
+// 決まった数のワーカーでイベントを処理する
+type dispatcher struct {
+	queues []chan bot.Event
+	key    DispatchKeyFunc
+	run    func(bot.Event)
+	next   atomic.Uint64
+	wg     sync.WaitGroup
+	closed sync.Once
+
+	queued    atomic.Int64
+	inFlight  atomic.Int64
+	processed atomic.Uint64
+	blocked   atomic.Uint64
+}
+
+func newDispatcher(config DispatcherConfig, run func(bot.Event)) *dispatcher {
+	if config.Workers <= 0 {
+		config.Workers = runtime.NumCPU() * 4
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 256
+	}
+	if config.Key == nil {
+		config.Key = DefaultDispatchKey
+	}
+	d := &dispatcher{
+		queues: make([]chan bot.Event, config.Workers),
+		key:    config.Key,
+		run:    run,
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan bot.Event, config.QueueSize)
+		d.wg.Add(1)
+		go d.work(d.queues[i])
+	}
+	return d
+}
+
+func (d *dispatcher) work(queue chan bot.Event) {
+	defer d.wg.Done()
+	for event := range queue {
+		d.queued.Add(-1)
+		d.inFlight.Add(1)
+		d.run(event)
+		d.inFlight.Add(-1)
+		d.processed.Add(1)
+	}
+}
+
+func (d *dispatcher) dispatch(event bot.Event) {
+	var i uint64
+	if key, ok := d.key(event); ok {
+		i = uint64(key) % uint64(len(d.queues))
+	} else {
+		i = d.next.Add(1) % uint64(len(d.queues))
+	}
+	queue := d.queues[i]
+	d.queued.Add(1)
+	select {
+	case queue <- event:
+	default:
+		d.blocked.Add(1)
+		queue <- event
+	}
+}
+
+// キューを閉じ、ワーカーが終了するのを待つ
+func (d *dispatcher) close() {
+	d.closed.Do(func() {
+		for _, queue := range d.queues {
+			close(queue)
+		}
+	})
+	d.wg.Wait()
+}
+
+func (d *dispatcher) stats() DispatcherStats {
+	return DispatcherStats{
+		Workers:   len(d.queues),
+		QueueSize: cap(d.queues[0]),
+		Queued:    d.queued.Load(),
+		InFlight:  d.inFlight.Load(),
+		Processed: d.processed.Load(),
+		Blocked:   d.blocked.Load(),
+	}
+}
+
+// イベントをワーカープールで処理するようにする
+// 開始後はASyncに関わらずワーカープールで処理される
+func (h *Handler) StartDispatcher(config DispatcherConfig) {
+	h.dispatchMu.Lock()
+	defer h.dispatchMu.Unlock()
+	if h.dispatcher != nil {
+		return
+	}
+	h.dispatcher = newDispatcher(config, func(event bot.Event) {
+		defer h.inFlight.Done()
+		h.dispatch(event)
+	})
+}
+
+// ワーカープールの状態を返す
+// ワーカープールを開始していない場合はokがfalseになる
+func (h *Handler) DispatcherStats() (stats DispatcherStats, ok bool) {
+	h.dispatchMu.RLock()
+	defer h.dispatchMu.RUnlock()
+	if h.dispatcher == nil {
+		return DispatcherStats{}, false
+	}
+	return h.dispatcher.stats(), true
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/log"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/events"
+)
+
+type dispatchTestEvent struct {
+	bot.Event
+	key snowflake.ID
+	seq int
+}
+
+func dispatchTestKey(event bot.Event) (snowflake.ID, bool) {
+	e, ok := event.(*dispatchTestEvent)
+	if !ok {
+		return 0, false
+	}
+	return e.key, true
+}
+
+func TestDispatcherOrder(t *testing.T) {
+	var mu sync.Mutex
+	got := map[snowflake.ID][]int{}
+	d := newDispatcher(DispatcherConfig{Workers: 4, QueueSize: 1, Key: dispatchTestKey}, func(event bot.Event) {
+		e := event.(*dispatchTestEvent)
+		mu.Lock()
+		got[e.key] = append(got[e.key], e.seq)
+		mu.Unlock()
+	})
+	for seq := 0; seq < 100; seq++ {
+		for key := snowflake.ID(1); key <= 8; key++ {
+			d.dispatch(&dispatchTestEvent{key: key, seq: seq})
+		}
+	}
+	d.close()
+
+	for key, seqs := range got {
+		if len(seqs) != 100 {
+			t.Fatalf("key %d: expected 100 events, got %d", key, len(seqs))
+		}
+		for i, seq := range seqs {
+			if seq != i {
+				t.Fatalf("key %d: events out of order: %v", key, seqs)
+			}
+		}
+	}
+	stats := d.stats()
+	if stats.Workers != 4 || stats.QueueSize != 1 || stats.Processed != 800 || stats.Queued != 0 || stats.InFlight != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestShutdownDrainsDispatcher(t *testing.T) {
+	h := New(log.Default())
+	h.StartDispatcher(DispatcherConfig{Workers: 2, Key: dispatchTestKey})
+	release := make(chan struct{})
+	var processed int
+	var mu sync.Mutex
+	h.dispatcher.run = func(event bot.Event) {
+		defer h.inFlight.Done()
+		<-release
+		mu.Lock()
+		processed++
+		mu.Unlock()
+	}
+	for i := 0; i < 10; i++ {
+		h.OnEvent(&dispatchTestEvent{key: snowflake.ID(i)})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := h.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	// 停止後のイベントは破棄される
+	h.OnEvent(&dispatchTestEvent{})
+
+	close(release)
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if processed != 10 {
+		t.Errorf("expected 10 processed events, got %d", processed)
+	}
+	if stats, ok := h.DispatcherStats(); !ok || stats.Processed != 10 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+// 同じチャンネルの次のメッセージを待つハンダラがワーカーを塞いでも待っているイベントは届く
+func TestDispatcherWaitForSameKey(t *testing.T) {
+	h := New(log.Default())
+	h.StartDispatcher(DispatcherConfig{Workers: 1})
+	defer h.Shutdown(context.Background())
+	result := make(chan string, 1)
+	h.AddMessages(Message{
+		Handler: func(event *events.GuildMessageCreate) error {
+			if event.Message.Content != "first" {
+				return nil
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			next, err := WaitFor(ctx, h, func(e *events.GuildMessageCreate) bool {
+				return e.ChannelID == event.ChannelID && e.Message.Content != "first"
+			})
+			if err != nil {
+				result <- err.Error()
+				return nil
+			}
+			result <- next.Message.Content
+			return nil
+		},
+	})
+	first := newGuildMessageCreate(1)
+	first.Message.Content = "first"
+	h.OnEvent(first)
+	waitWaiters(t, h, 1)
+	second := newGuildMessageCreate(1)
+	second.Message.Content = "second"
+	h.OnEvent(second)
+	if got := <-result; got != "second" {
+		t.Errorf("expected the second message, got %q", got)
+	}
+}
+
+func TestDefaultDispatchKeyInteractions(t *testing.T) {
+	if _, ok := DefaultDispatchKey(&events.ComponentInteractionCreate{}); ok {
+		t.Error("expected interactions not to be keyed")
+	}
+	if key, ok := DefaultDispatchKey(newGuildMessageCreate(3)); !ok || key != 3 {
+		t.Errorf("expected messages to be keyed by channel, got %d %v", key, ok)
+	}
+}
+
+// 待機が登録されるまで待つ
+func waitWaiters(t *testing.T, h *Handler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		h.mu.RLock()
+		registered := len(h.waiters)
+		h.mu.RUnlock()
+		if registered >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiters, got %d", n, registered)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/google/uuid"
+	"github.com/sabafly/sabafly-disgo/events"
+)
+
+type (
+	DMMessageHandler        func(event *events.DMMessageCreate) error
+	DMMessageContextHandler func(ctx context.Context, event *events.DMMessageCreate) error
+)
+
+func (f DMMessageHandler) WithContext() DMMessageContextHandler {
+	return func(_ context.Context, event *events.DMMessageCreate) error {
+		return f(event)
+	}
+}
+
+// DMで作成されたメッセージのハンダラ
+type DMMessage struct {
+	UUID      *uuid.UUID
+	ChannelID *snowflake.ID
+	AuthorID  *snowflake.ID
+	Check     Check[*events.DMMessageCreate]
+	Handler   DMMessageHandler
+
+	ContextHandler DMMessageContextHandler
+}
+
+func (m DMMessage) handler() DMMessageContextHandler {
+	if m.ContextHandler != nil {
+		return m.ContextHandler
+	}
+	if m.Handler != nil {
+		return m.Handler.WithContext()
+	}
+	return nil
+}
+
+func (h *Handler) handleDMMessage(ctx context.Context, event *events.DMMessageCreate) {
+	if h.isExcluded(event.ChannelID) {
+		return
+	}
+	h.Logger.Debugf("DMメッセージ作成 %d", event.ChannelID)
+	for _, m := range h.dmMessages() {
+		h.invoke("dm message handler", event, func() error {
+			h.run_dm_message(ctx, m, event)
+			return nil
+		})
+	}
+}
+
+func (h *Handler) run_dm_message(ctx context.Context, m DMMessage, event *events.DMMessageCreate) {
+	if m.ChannelID != nil && *m.ChannelID != event.ChannelID {
+		h.Logger.Debug("チャンネルが違います")
+		return
+	}
+	if m.AuthorID != nil && *m.AuthorID != event.Message.Author.ID {
+		h.Logger.Debugf("送信者が違います %d %d", *m.AuthorID, event.Message.Author.ID)
+		return
+	}
+	if !m.Check.Run(event).Allowed {
+		return
+	}
+	handler := m.handler()
+	if handler == nil {
+		return
+	}
+	if err := handler(ctx, event); err != nil {
+		h.Logger.Errorf("Failed to handle DM message \"%d\" in %s: %s", event.MessageID, event.ChannelID, err.Error())
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/google/uuid"
+	"github.com/sabafly/sabafly-disgo/events"
+)
+
+type (
+	DMMessageDeleteHandler        func(event *events.DMMessageDelete) error
+	DMMessageDeleteContextHandler func(ctx context.Context, event *events.DMMessageDelete) error
+)
+
+func (f DMMessageDeleteHandler) WithContext() DMMessageDeleteContextHandler {
+	return func(_ context.Context, event *events.DMMessageDelete) error {
+		return f(event)
+	}
+}
+
+// DMで削除されたメッセージのハンダラ
+type DMMessageDelete struct {
+	UUID      *uuid.UUID
+	ChannelID *snowflake.ID
+	AuthorID  *snowflake.ID
+	Check     Check[*events.DMMessageDelete]
+	Handler   DMMessageDeleteHandler
+
+	ContextHandler DMMessageDeleteContextHandler
+}
+
+func (m DMMessageDelete) handler() DMMessageDeleteContextHandler {
+	if m.ContextHandler != nil {
+		return m.ContextHandler
+	}
+	if m.Handler != nil {
+		return m.Handler.WithContext()
+	}
+	return nil
+}
+
+func (h *Handler) handleDMMessageDelete(ctx context.Context, event *events.DMMessageDelete) {
+	if h.isExcluded(event.ChannelID) {
+		return
+	}
+	h.Logger.Debugf("DMメッセージ削除 %d", event.ChannelID)
+	for _, m := range h.dmMessageDeletes() {
+		h.invoke("dm message delete handler", event, func() error {
+			h.run_dm_message_delete(ctx, m, event)
+			return nil
+		})
+	}
+}
+
+func (h *Handler) run_dm_message_delete(ctx context.Context, m DMMessageDelete, event *events.DMMessageDelete) {
+	if m.ChannelID != nil && *m.ChannelID != event.ChannelID {
+		h.Logger.Debug("チャンネルが違います")
+		return
+	}
+	if m.AuthorID != nil && *m.AuthorID != event.Message.Author.ID {
+		h.Logger.Debugf("送信者が違います %d %d", *m.AuthorID, event.Message.Author.ID)
+		return
+	}
+	if !m.Check.Run(event).Allowed {
+		return
+	}
+	handler := m.handler()
+	if handler == nil {
+		return
+	}
+	if err := handler(ctx, event); err != nil {
+		h.Logger.Errorf("Failed to handle DM message delete \"%d\" in %s: %s", event.MessageID, event.ChannelID, err.Error())
+	}
+}
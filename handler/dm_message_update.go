@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/google/uuid"
+	"github.com/sabafly/sabafly-disgo/events"
+)
+
+type (
+	DMMessageUpdateHandler        func(event *events.DMMessageUpdate) error
+	DMMessageUpdateContextHandler func(ctx context.Context, event *events.DMMessageUpdate) error
+)
+
+func (f DMMessageUpdateHandler) WithContext() DMMessageUpdateContextHandler {
+	return func(_ context.Context, event *events.DMMessageUpdate) error {
+		return f(event)
+	}
+}
+
+// DMで編集されたメッセージのハンダラ
+type DMMessageUpdate struct {
+	UUID      *uuid.UUID
+	ChannelID *snowflake.ID
+	AuthorID  *snowflake.ID
+	Check     Check[*events.DMMessageUpdate]
+	Handler   DMMessageUpdateHandler
+
+	ContextHandler DMMessageUpdateContextHandler
+}
+
+func (m DMMessageUpdate) handler() DMMessageUpdateContextHandler {
+	if m.ContextHandler != nil {
+		return m.ContextHandler
+	}
+	if m.Handler != nil {
+		return m.Handler.WithContext()
+	}
+	return nil
+}
+
+func (h *Handler) handleDMMessageUpdate(ctx context.Context, event *events.DMMessageUpdate) {
+	if h.isExcluded(event.ChannelID) {
+		return
+	}
+	h.Logger.Debugf("DMメッセージ更新 %d", event.ChannelID)
+	for _, m := range h.dmMessageUpdates() {
+		h.invoke("dm message update handler", event, func() error {
+			h.run_dm_message_update(ctx, m, event)
+			return nil
+		})
+	}
+}
+
+func (h *Handler) run_dm_message_update(ctx context.Context, m DMMessageUpdate, event *events.DMMessageUpdate) {
+	if m.ChannelID != nil && *m.ChannelID != event.ChannelID {
+		h.Logger.Debug("チャンネルが違います")
+		return
+	}
+	if m.AuthorID != nil && *m.AuthorID != event.Message.Author.ID {
+		h.Logger.Debugf("送信者が違います %d %d", *m.AuthorID, event.Message.Author.ID)
+		return
+	}
+	if !m.Check.Run(event).Allowed {
+		return
+	}
+	handler := m.handler()
+	if handler == nil {
+		return
+	}
+	if err := handler(ctx, event); err != nil {
+		h.Logger.Errorf("Failed to handle DM message update \"%d\" in %s: %s", event.MessageID, event.ChannelID, err.Error())
+	}
+}
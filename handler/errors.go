@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-lib/v2/translate"
+)
+
+// エラーの種類
+type ErrorKind int
+
+const (
+	// 想定外のエラー
+	ErrorKindInternal ErrorKind = iota
+	// ユーザーの入力や操作によるエラー
+	ErrorKindUser
+	// 権限が足りない
+	ErrorKindPermission
+	// 対象が見つからない
+	ErrorKindNotFound
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindUser:
+		return "user"
+	case ErrorKindPermission:
+		return "permission"
+	case ErrorKindNotFound:
+		return "not found"
+	default:
+		return "internal"
+	}
+}
+
+// 種類ごとの既定の翻訳キー
+const (
+	InternalErrorMessage    = "error_occurred"
+	PermissionDeniedMessage = "permission_denied"
+	NotFoundMessage         = "not_found"
+)
+
+// ハンダラが返すエラー
+// ディスパッチャが種類と翻訳キーに応じたエラーメッセージを応答する
+type Error struct {
+	Kind ErrorKind
+	// エラーメッセージの翻訳キー
+	Key string
+	// 翻訳のテンプレートデータ
+	Data any
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s error %s: %s", e.Kind, e.Key, e.Err)
+	}
+	return fmt.Sprintf("%s error %s", e.Kind, e.Key)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(kind ErrorKind, key string, data []any) *Error {
+	e := &Error{Kind: kind, Key: key}
+	if len(data) != 0 {
+		e.Data = data[0]
+	}
+	return e
+}
+
+// ユーザーによるエラーを作成する
+func UserError(key string, data ...any) *Error {
+	return newError(ErrorKindUser, key, data)
+}
+
+// 権限が足りないエラーを作成する
+// keyが空の場合はPermissionDeniedMessageを使う
+func PermissionDenied(key string, data ...any) *Error {
+	if key == "" {
+		key = PermissionDeniedMessage
+	}
+	return newError(ErrorKindPermission, key, data)
+}
+
+// 対象が見つからないエラーを作成する
+// keyが空の場合はNotFoundMessageを使う
+func NotFound(key string, data ...any) *Error {
+	if key == "" {
+		key = NotFoundMessage
+	}
+	return newError(ErrorKindNotFound, key, data)
+}
+
+// 想定外のエラーを作成する
+func Internal(err error) *Error {
+	return &Error{Kind: ErrorKindInternal, Key: InternalErrorMessage, Err: err}
+}
+
+// エラーをErrorに変換する
+// Errorでないエラーは想定外のエラーとして扱う
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var optionErr *OptionError
+	if errors.As(err, &optionErr) {
+		return &Error{Kind: ErrorKindUser, Key: optionErr.Key, Data: optionErr.Data, Err: optionErr}
+	}
+	return Internal(err)
+}
+
+// ハンダラが返したエラーを処理する
+// nilを返した場合は処理済みとして扱い、エラーを返した場合は次の処理に渡す
+type ErrorHandler func(ctx context.Context, event bot.Event, err error) error
+
+// エラーを応答する埋め込みを作成する
+type ErrorEmbedsFunc func(locale discord.Locale, err *Error) []discord.Embed
+
+func (h *Handler) errorEmbeds(locale discord.Locale, err *Error) []discord.Embed {
+	if h.ErrorEmbeds != nil {
+		return h.ErrorEmbeds(locale, err)
+	}
+	return defaultErrorEmbeds(locale, err.Key, err.Data)
+}
+
+func defaultErrorEmbeds(locale discord.Locale, key string, data any) []discord.Embed {
+	return []discord.Embed{
+		{
+			Title:       translate.Message(locale, key+"_title"),
+			Description: translate.Message(locale, key+"_message", translate.WithTemplate(data)),
+			Color:       0xff0000,
+		},
+	}
+}
+
+// ハンダラのOnError、HandlerのOnErrorの順にエラーを渡し、
+// 処理されなかった場合はエラーの種類に応じたメッセージを応答する
+func (h *Handler) handleError(ctx context.Context, event bot.Event, locale discord.Locale, tracker *ackTracker, onError ErrorHandler, source string, err error) {
+	for _, handle := range []ErrorHandler{onError, h.OnError} {
+		if handle == nil {
+			continue
+		}
+		if err = handle(ctx, event, err); err == nil {
+			return
+		}
+	}
+	e := AsError(err)
+	if e.Kind == ErrorKindInternal {
+		h.Logger.Errorf("Failed to handle %s: %s", source, err)
+	} else {
+		h.Logger.Debugf("%s でエラー: %s", source, err)
+	}
+	if err := tracker.reply(discord.MessageCreate{
+		Embeds: h.errorEmbeds(locale, e),
+		Flags:  discord.MessageFlagEphemeral,
+	}); err != nil {
+		h.Logger.Errorf("Failed to reply error for %s: %s", source, err)
+	}
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/log"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/rest"
+)
+
+type testInteraction struct{}
+
+func (testInteraction) ApplicationID() snowflake.ID { return 0 }
+func (testInteraction) Token() string               { return "" }
+func (testInteraction) CreatedAt() time.Time        { return time.Now() }
+
+func TestAsError(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		kind ErrorKind
+		key  string
+	}{
+		"user":       {UserError("invalid_duration"), ErrorKindUser, "invalid_duration"},
+		"wrapped":    {errors.Join(errors.New("context"), NotFound("")), ErrorKindNotFound, NotFoundMessage},
+		"permission": {PermissionDenied(""), ErrorKindPermission, PermissionDeniedMessage},
+		"option":     {&OptionError{Option: "days", Key: OptionErrorOutOfRange}, ErrorKindUser, OptionErrorOutOfRange},
+		"internal":   {errors.New("boom"), ErrorKindInternal, InternalErrorMessage},
+	}
+	for name, tt := range tests {
+		e := AsError(tt.err)
+		if e.Kind != tt.kind || e.Key != tt.key {
+			t.Errorf("%s: expected %s %s, got %s %s", name, tt.kind, tt.key, e.Kind, e.Key)
+		}
+	}
+}
+
+func TestHandleError(t *testing.T) {
+	h := New(log.Default())
+	var replies []discord.MessageCreate
+	tracker := h.newAckTracker(nil, testInteraction{}, func(responseType discord.InteractionResponseType, data discord.InteractionResponseData, _ ...rest.RequestOpt) error {
+		replies = append(replies, data.(discord.MessageCreate))
+		return nil
+	})
+	var global int
+	h.OnError = func(_ context.Context, _ bot.Event, err error) error {
+		global++
+		return err
+	}
+	h.ErrorEmbeds = func(_ discord.Locale, err *Error) []discord.Embed {
+		return []discord.Embed{{Title: err.Key}}
+	}
+	onError := func(_ context.Context, _ bot.Event, err error) error {
+		return UserError("converted")
+	}
+	h.handleError(context.Background(), nil, discord.LocaleJapanese, tracker, onError, "test", errors.New("boom"))
+	if global != 1 {
+		t.Errorf("expected global OnError to be called once, got %d", global)
+	}
+	if len(replies) != 1 || replies[0].Embeds[0].Title != "converted" || replies[0].Flags != discord.MessageFlagEphemeral {
+		t.Fatalf("unexpected replies %+v", replies)
+	}
+	if !tracker.Acknowledged() {
+		t.Error("expected interaction to be acknowledged")
+	}
+
+	handled := func(context.Context, bot.Event, error) error { return nil }
+	h.handleError(context.Background(), nil, discord.LocaleJapanese, tracker, handled, "test", errors.New("boom"))
+	if global != 1 || len(replies) != 1 {
+		t.Error("expected handled error to stop the pipeline")
+	}
+}
@@ -1,27 +1,60 @@
 package handler
 
 import (
+	"context"
+
 	"github.com/google/uuid"
 	"github.com/sabafly/sabafly-disgo/bot"
 )
 
-type RawHandler func(event bot.Event) error
+type (
+	RawHandler        func(event bot.Event) error
+	RawContextHandler func(ctx context.Context, event bot.Event) error
+)
+
+func (f RawHandler) WithContext() RawContextHandler {
+	return func(_ context.Context, event bot.Event) error {
+		return f(event)
+	}
+}
 
 type Event struct {
 	ID *uuid.UUID
 
 	Check   Check[bot.Event]
 	Handler RawHandler
+
+	ContextHandler RawContextHandler
+}
+
+func (e Event) handler() RawContextHandler {
+	if e.ContextHandler != nil {
+		return e.ContextHandler
+	}
+	if e.Handler != nil {
+		return e.Handler.WithContext()
+	}
+	return nil
 }
 
-func (h *Handler) handleEvent(event bot.Event) {
-	for _, e := range h.Event {
-		if e.Check != nil && !e.Check(event) {
-			continue
-		}
+func (h *Handler) handleEvent(ctx context.Context, event bot.Event) {
+	for _, e := range h.events() {
+		h.invoke("raw event handler", event, func() error {
+			h.runEvent(ctx, e, event)
+			return nil
+		})
+	}
+}
 
-		if err := e.Handler(event); err != nil {
-			h.Logger.Errorf("Failed to handle raw event: %v", event)
-		}
+func (h *Handler) runEvent(ctx context.Context, e Event, event bot.Event) {
+	if !e.Check.Run(event).Allowed {
+		return
+	}
+	handler := e.handler()
+	if handler == nil {
+		return
+	}
+	if err := handler(ctx, event); err != nil {
+		h.Logger.Errorf("Failed to handle raw event %T: %s", event, err)
 	}
 }
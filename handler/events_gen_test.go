@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/disgoorg/log"
+	"github.com/sabafly/sabafly-disgo/bot"
+)
+
+// すべてのハンダラが初期化され、対応するイベントが渡されることを確認する
+func TestEventHandlersDispatch(t *testing.T) {
+	h := New(log.Default())
+	lists := reflect.ValueOf(&h.EventHandlers).Elem()
+	for i := 0; i < lists.NumField(); i++ {
+		name := lists.Type().Field(i).Name
+		list := lists.Field(i)
+		if list.IsNil() {
+			t.Errorf("%s is not initialized", name)
+			continue
+		}
+
+		// Generics[T]を作成してハンダラを登録する
+		generics := reflect.New(list.Elem().FieldByName("Array").Type().Elem()).Elem()
+		handlerType := generics.FieldByName("ContextHandler").Type()
+		var called bool
+		generics.FieldByName("ContextHandler").Set(reflect.MakeFunc(handlerType, func([]reflect.Value) []reflect.Value {
+			called = true
+			return []reflect.Value{reflect.Zero(handlerType.Out(0))}
+		}))
+		list.MethodByName("Adds").Call([]reflect.Value{generics})
+
+		event, ok := reflect.New(handlerType.In(1).Elem()).Interface().(bot.Event)
+		if !ok {
+			t.Errorf("%s: %s is not an event", name, handlerType.In(1))
+			continue
+		}
+		if !h.EventHandlers.dispatchEvent(context.Background(), event) || !called {
+			t.Errorf("%s: %T was not dispatched", name, event)
+		}
+	}
+}
@@ -1,31 +1,71 @@
 package handler
 
 import (
+	"context"
+	"fmt"
+	"sync"
+
 	"github.com/disgoorg/log"
 	"github.com/google/uuid"
+	"github.com/sabafly/sabafly-disgo/bot"
+)
+
+type (
+	GenericsHandler[T any]        func(event *T) error
+	GenericsContextHandler[T any] func(ctx context.Context, event *T) error
 )
 
-type GenericsHandler[T any] func(event *T) error
+func (f GenericsHandler[T]) WithContext() GenericsContextHandler[T] {
+	return func(_ context.Context, event *T) error {
+		return f(event)
+	}
+}
 
 type Generics[T any] struct {
 	ID *uuid.UUID
 
 	Check   Check[*T]
 	Handler GenericsHandler[T]
+
+	ContextHandler GenericsContextHandler[T]
+}
+
+func (g Generics[T]) handler() GenericsContextHandler[T] {
+	if g.ContextHandler != nil {
+		return g.ContextHandler
+	}
+	if g.Handler != nil {
+		return g.Handler.WithContext()
+	}
+	return nil
 }
 
 type genericsList[T any] struct {
+	mu sync.RWMutex
+
 	Map   map[uuid.UUID]Generics[T]
 	Array []Generics[T]
 
-	Logger log.Logger
+	Logger  log.Logger
+	handler *Handler
+}
+
+func newGenericsList[T any](h *Handler) *genericsList[T] {
+	return &genericsList[T]{
+		Map:     map[uuid.UUID]Generics[T]{},
+		Array:   []Generics[T]{},
+		Logger:  h.Logger,
+		handler: h,
+	}
 }
 
 func (g *genericsList[T]) Add(gen Generics[T]) func() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	if gen.ID != nil {
 		g.Map[*gen.ID] = gen
 		return func() {
-			delete(g.Map, *gen.ID)
+			g.Remove(*gen.ID)
 		}
 	} else {
 		g.Array = append(g.Array, gen)
@@ -34,23 +74,43 @@ func (g *genericsList[T]) Add(gen Generics[T]) func() {
 }
 
 func (g *genericsList[T]) Adds(gen ...Generics[T]) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	g.Array = append(g.Array, gen...)
 }
 
-func (g *genericsList[T]) handleEvent(event *T) {
+func (g *genericsList[T]) Remove(id uuid.UUID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.Map, id)
+}
+
+func (g *genericsList[T]) handleEvent(ctx context.Context, event *T) {
+	g.mu.RLock()
+	generics := make([]Generics[T], 0, len(g.Map)+len(g.Array))
 	for _, gen := range g.Map {
-		g.run(gen, event)
+		generics = append(generics, gen)
 	}
-	for _, gen := range g.Array {
-		g.run(gen, event)
+	generics = append(generics, g.Array...)
+	g.mu.RUnlock()
+	ev, _ := any(event).(bot.Event)
+	for _, gen := range generics {
+		g.handler.invoke(fmt.Sprintf("%T handler", *event), ev, func() error {
+			g.run(ctx, gen, event)
+			return nil
+		})
 	}
 }
 
-func (g *genericsList[T]) run(generic Generics[T], event *T) {
-	if generic.Check != nil && generic.Check(event) {
+func (g *genericsList[T]) run(ctx context.Context, generic Generics[T], event *T) {
+	if !generic.Check.Run(event).Allowed {
+		return
+	}
+	handler := generic.handler()
+	if handler == nil {
 		return
 	}
-	if err := generic.Handler(event); err != nil {
+	if err := handler(ctx, event); err != nil {
 		g.Logger.Errorf("failed to handle event %T: %s", *event, err.Error())
 		return
 	}
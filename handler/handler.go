@@ -17,6 +17,11 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package handler
 
 import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
 	"github.com/disgoorg/log"
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/google/uuid"
@@ -25,106 +30,263 @@ import (
 	"github.com/sabafly/sabafly-disgo/events"
 )
 
+//go:generate go run ./internal/eventgen
+
 var _ bot.EventListener = (*Handler)(nil)
 
 func New(logger log.Logger) *Handler {
-	return &Handler{
-		Logger:     logger,
-		Commands:   map[string]Command{},
-		Components: map[string]Component{},
-		Modals:     map[string]Modal{},
-		Message:    map[uuid.UUID]Message{},
-		Ready:      []func(*events.Ready){},
-		MemberJoin: genericsList[events.GuildMemberJoin]{
-			Map:   map[uuid.UUID]Generics[events.GuildMemberJoin]{},
-			Array: []Generics[events.GuildMemberJoin]{},
-		},
-		MemberLeave: genericsList[events.GuildMemberLeave]{
-			Map:   map[uuid.UUID]Generics[events.GuildMemberLeave]{},
-			Array: []Generics[events.GuildMemberLeave]{},
-		},
-		MessageReactionAdd: genericsList[events.GuildMessageReactionAdd]{
-			Map:   map[uuid.UUID]Generics[events.GuildMessageReactionAdd]{},
-			Array: []Generics[events.GuildMessageReactionAdd]{},
-		},
-		MessageReactionRemove: genericsList[events.GuildMessageReactionRemove]{
-			Map:   map[uuid.UUID]Generics[events.GuildMessageReactionRemove]{},
-			Array: []Generics[events.GuildMessageReactionRemove]{},
-		},
-
-		ExcludeID: map[snowflake.ID]struct{}{},
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Handler{
+		ctx:           ctx,
+		cancel:        cancel,
+		Logger:        logger,
+		Commands:      map[string]Command{},
+		Components:    map[string]Component{},
+		Modals:        map[string]Modal{},
+		Message:       map[uuid.UUID]Message{},
+		MessageUpdate: map[uuid.UUID]MessageUpdate{},
+		MessageDelete: map[uuid.UUID]MessageDelete{},
+		Ready:         []func(*events.Ready){},
+		EventMap:      map[uuid.UUID]Event{},
+
+		DMMessage:       map[uuid.UUID]DMMessage{},
+		DMMessageUpdate: map[uuid.UUID]DMMessageUpdate{},
+		DMMessageDelete: map[uuid.UUID]DMMessageDelete{},
+
+		ExcludeID:     map[snowflake.ID]struct{}{},
+		syncedGuilds:  map[snowflake.ID]struct{}{},
+		waiters:       map[uuid.UUID]waiter{},
+		StateStore:    NewCacheStateStore(DefaultStateTTL),
+		CooldownStore: NewMemoryCooldownStore(),
 	}
+	h.EventHandlers.init(h)
+	return h
 }
 
+// 各ハンダラの登録と削除はAdd*、Remove*メソッドを通して行う
+// マップを直接変更するとイベントの処理と競合する
 type Handler struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.RWMutex
+	syncMu sync.Mutex
+	// イベントを受け取った時点で渡す待機
+	waiters map[uuid.UUID]waiter
+	// 前回までにコマンドを同期したギルド
+	syncedGuilds map[snowflake.ID]struct{}
+
+	dispatchMu sync.RWMutex
+	dispatcher *dispatcher
+	inFlight   sync.WaitGroup
+	shutdown   bool
+
 	Logger log.Logger
 
-	Commands                   map[string]Command
-	Components                 map[string]Component
-	Modals                     map[string]Modal
-	Message                    map[uuid.UUID]Message
-	MessageUpdate              map[uuid.UUID]MessageUpdate
-	MessageDelete              map[uuid.UUID]MessageDelete
-	Ready                      []func(*events.Ready)
-	MemberJoin                 genericsList[events.GuildMemberJoin]
-	MemberLeave                genericsList[events.GuildMemberLeave]
-	MemberUpdate               genericsList[events.GuildMemberUpdate]
-	MessageReactionAdd         genericsList[events.GuildMessageReactionAdd]
-	MessageReactionRemove      genericsList[events.GuildMessageReactionRemove]
-	MessageReactionRemoveAll   genericsList[events.GuildMessageReactionRemoveAll]
-	MessageReactionRemoveEmoji genericsList[events.GuildMessageReactionRemoveEmoji]
-	Event                      []Event
+	Commands      map[string]Command
+	Components    map[string]Component
+	Modals        map[string]Modal
+	Message       map[uuid.UUID]Message
+	MessageUpdate map[uuid.UUID]MessageUpdate
+	MessageDelete map[uuid.UUID]MessageDelete
+
+	DMMessage       map[uuid.UUID]DMMessage
+	DMMessageUpdate map[uuid.UUID]DMMessageUpdate
+	DMMessageDelete map[uuid.UUID]DMMessageDelete
+
+	Ready []func(*events.Ready)
+	Event []Event
+	// IDを指定して登録した一時的なハンダラ
+	EventMap    map[uuid.UUID]Event
+	Middlewares []Middleware
+
+	EventHandlers
 
 	Static StaticHandler
 
-	ExcludeID  map[snowflake.ID]struct{}
-	DevGuildID []snowflake.ID
-	IsDebug    bool
-	ASync      bool
-	IsLogEvent bool
+	ExcludeID    map[snowflake.ID]struct{}
+	DevGuildID   []snowflake.ID
+	IsDebug      bool
+	ASync        bool
+	IsLogEvent   bool
+	EventTimeout time.Duration
+	// インタラクションの作成から自動で遅延応答を送るまでの時間
+	// 0の場合はDefaultAutoDeferTimeout、負の値の場合は自動で遅延応答しない
+	AutoDeferTimeout time.Duration
+	// ハンダラが返したエラーを処理する
+	// 各ハンダラのOnErrorの後に呼ばれる
+	OnError ErrorHandler
+	// エラーを応答する埋め込みを作成する
+	// nilの場合は翻訳キーのメッセージをそのまま埋め込みにする
+	ErrorEmbeds ErrorEmbedsFunc
+	// 翻訳キーからエラーメッセージを返す関数
+	// nilの場合は翻訳したメッセージをそのまま埋め込みにして返す
+	ErrorMessage ErrorMessageFunc
+	// ハンダラで発生したパニックの報告先
+	PanicReporters []PanicReporter
+	// カスタムIDの引数を署名する鍵
+	// nilでない場合、署名が正しくない引数付きのカスタムIDは拒否される
+	CustomIDKey []byte
+	// コンポーネントやモーダルの状態を保存するストア
+	StateStore StateStore
+	// 状態が失われていた場合の応答
+	// nilの場合はStateExpiredMessageのエラーメッセージを返す
+	StateExpired StateExpiredFunc
+	// クールダウンのバケットを保存するストア
+	CooldownStore CooldownStore
+	// SyncCommandsで使う同期の設定
+	SyncOptions SyncOptions
 }
 
 type StaticHandler struct {
 	Message       []Message
 	MessageUpdate []MessageUpdate
 	MessageDelete []MessageDelete
+
+	DMMessage       []DMMessage
+	DMMessageUpdate []DMMessageUpdate
+	DMMessageDelete []DMMessageDelete
 }
 
 func (h *Handler) AddExclude(ids ...snowflake.ID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	for _, id := range ids {
 		h.ExcludeID[id] = struct{}{}
 	}
 }
 
+func (h *Handler) isExcluded(id snowflake.ID) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, ok := h.ExcludeID[id]
+	return ok
+}
+
 func (h *Handler) AddCommands(commands ...Command) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	for _, command := range commands {
-		h.Commands[command.Create.CommandName()] = command
+		h.Commands[registeredCommandKey(command.Create.Type(), command.Create.CommandName())] = command
+	}
+}
+
+// 同じ名前のスラッシュコマンド、ユーザーコマンド、メッセージコマンドを全て削除する
+func (h *Handler) RemoveCommand(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, t := range []discord.ApplicationCommandType{discord.ApplicationCommandTypeSlash, discord.ApplicationCommandTypeUser, discord.ApplicationCommandTypeMessage} {
+		delete(h.Commands, registeredCommandKey(t, name))
+	}
+}
+
+// ユーザーコマンドを削除する
+func (h *Handler) RemoveUserCommand(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.Commands, registeredCommandKey(discord.ApplicationCommandTypeUser, name))
+}
+
+// メッセージコマンドを削除する
+func (h *Handler) RemoveMessageCommand(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.Commands, registeredCommandKey(discord.ApplicationCommandTypeMessage, name))
+}
+
+// Commandsのキー
+// スラッシュコマンドは名前、それ以外は同じ名前のスラッシュコマンドと区別するため種類を付ける
+func registeredCommandKey(t discord.ApplicationCommandType, name string) string {
+	if t == discord.ApplicationCommandTypeSlash {
+		return name
 	}
+	return commandKey(t, name)
+}
+
+func (h *Handler) command(name string) (Command, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	command, ok := h.Commands[name]
+	return command, ok
+}
+
+func (h *Handler) commands() []Command {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	commands := make([]Command, 0, len(h.Commands))
+	for _, command := range h.Commands {
+		commands = append(commands, command)
+	}
+	return commands
 }
 
 func (h *Handler) AddComponents(components ...Component) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	for _, component := range components {
 		h.Components[component.Name] = component
 	}
 }
 
 func (h *Handler) AddComponent(component Component) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	h.Components[component.Name] = component
 	return func() {
-		delete(h.Components, component.Name)
+		h.RemoveComponent(component.Name)
 	}
 }
 
+func (h *Handler) RemoveComponent(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.Components, name)
+}
+
+func (h *Handler) component(name string) (Component, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	component, ok := h.Components[name]
+	return component, ok
+}
+
 func (h *Handler) AddModals(modals ...Modal) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	for _, modal := range modals {
 		h.Modals[modal.Name] = modal
 	}
 }
 
+func (h *Handler) AddModal(modal Modal) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Modals[modal.Name] = modal
+	return func() {
+		h.RemoveModal(modal.Name)
+	}
+}
+
+func (h *Handler) RemoveModal(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.Modals, name)
+}
+
+func (h *Handler) modal(name string) (Modal, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	modal, ok := h.Modals[name]
+	return modal, ok
+}
+
 func (h *Handler) AddMessage(message Message) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	if message.UUID != nil {
 		h.Message[*message.UUID] = message
 		return func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
 			delete(h.Message, *message.UUID)
 		}
 	} else {
@@ -134,13 +296,25 @@ func (h *Handler) AddMessage(message Message) func() {
 }
 
 func (h *Handler) AddMessages(messages ...Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	h.Static.Message = append(h.Static.Message, messages...)
 }
 
+func (h *Handler) messages() []Message {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return snapshot(h.Static.Message, h.Message)
+}
+
 func (h *Handler) AddMessageUpdate(messageUpdate MessageUpdate) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	if messageUpdate.UUID != nil {
 		h.MessageUpdate[*messageUpdate.UUID] = messageUpdate
 		return func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
 			delete(h.MessageUpdate, *messageUpdate.UUID)
 		}
 	} else {
@@ -150,13 +324,25 @@ func (h *Handler) AddMessageUpdate(messageUpdate MessageUpdate) func() {
 }
 
 func (h *Handler) AddMessageUpdates(messageUpdates ...MessageUpdate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	h.Static.MessageUpdate = append(h.Static.MessageUpdate, messageUpdates...)
 }
 
+func (h *Handler) messageUpdates() []MessageUpdate {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return snapshot(h.Static.MessageUpdate, h.MessageUpdate)
+}
+
 func (h *Handler) AddMessageDelete(messageDelete MessageDelete) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	if messageDelete.UUID != nil {
 		h.MessageDelete[*messageDelete.UUID] = messageDelete
 		return func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
 			delete(h.MessageDelete, *messageDelete.UUID)
 		}
 	} else {
@@ -166,81 +352,203 @@ func (h *Handler) AddMessageDelete(messageDelete MessageDelete) func() {
 }
 
 func (h *Handler) AddMessageDeletes(messageDeletes ...MessageDelete) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	h.Static.MessageDelete = append(h.Static.MessageDelete, messageDeletes...)
 }
 
-func (h *Handler) AddEvent(events ...Event) {
-	h.Event = append(h.Event, events...)
+func (h *Handler) messageDeletes() []MessageDelete {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return snapshot(h.Static.MessageDelete, h.MessageDelete)
 }
 
-func (h *Handler) AddReady(ready func(*events.Ready)) {
-	h.Ready = append(h.Ready, ready)
+func (h *Handler) AddDMMessage(dmMessage DMMessage) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if dmMessage.UUID != nil {
+		h.DMMessage[*dmMessage.UUID] = dmMessage
+		return func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.DMMessage, *dmMessage.UUID)
+		}
+	} else {
+		h.Static.DMMessage = append(h.Static.DMMessage, dmMessage)
+		return nil
+	}
 }
 
-func (h *Handler) handleReady(e *events.Ready) {
-	for _, v := range h.Ready {
-		v(e)
-	}
+func (h *Handler) AddDMMessages(dmMessages ...DMMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Static.DMMessage = append(h.Static.DMMessage, dmMessages...)
 }
 
-func (h *Handler) SyncCommands(client bot.Client, guildIDs ...snowflake.ID) {
-	commands := []discord.ApplicationCommandCreate{}
-	devCommands := []discord.ApplicationCommandCreate{}
-	for _, command := range h.Commands {
-		if command.DevOnly {
-			devCommands = append(devCommands, command.Create)
-		} else {
-			commands = append(commands, command.Create)
+func (h *Handler) dmMessages() []DMMessage {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return snapshot(h.Static.DMMessage, h.DMMessage)
+}
+
+func (h *Handler) AddDMMessageUpdate(dmMessageUpdate DMMessageUpdate) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if dmMessageUpdate.UUID != nil {
+		h.DMMessageUpdate[*dmMessageUpdate.UUID] = dmMessageUpdate
+		return func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.DMMessageUpdate, *dmMessageUpdate.UUID)
 		}
+	} else {
+		h.Static.DMMessageUpdate = append(h.Static.DMMessageUpdate, dmMessageUpdate)
+		return nil
 	}
+}
 
-	if len(devCommands) > 0 {
-		for _, id := range h.DevGuildID {
-			if _, err := client.Rest().SetGuildCommands(client.ApplicationID(), id, devCommands); err != nil {
-				h.Logger.Errorf("Failed to sync %d commands: %s", id, err)
-			}
-			h.Logger.Infof("Synced %d guild %d commands", len(devCommands), id)
-			cmd, err := client.Rest().GetGuildCommands(client.ApplicationID(), id, true)
-			h.Logger.Debugf("%+v %s", *cmd[0].GuildID(), err)
+func (h *Handler) AddDMMessageUpdates(dmMessageUpdates ...DMMessageUpdate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Static.DMMessageUpdate = append(h.Static.DMMessageUpdate, dmMessageUpdates...)
+}
+
+func (h *Handler) dmMessageUpdates() []DMMessageUpdate {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return snapshot(h.Static.DMMessageUpdate, h.DMMessageUpdate)
+}
+
+func (h *Handler) AddDMMessageDelete(dmMessageDelete DMMessageDelete) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if dmMessageDelete.UUID != nil {
+		h.DMMessageDelete[*dmMessageDelete.UUID] = dmMessageDelete
+		return func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.DMMessageDelete, *dmMessageDelete.UUID)
 		}
+	} else {
+		h.Static.DMMessageDelete = append(h.Static.DMMessageDelete, dmMessageDelete)
+		return nil
 	}
+}
 
-	if len(guildIDs) == 0 {
-		if _, err := client.Rest().SetGlobalCommands(client.ApplicationID(), commands); err != nil {
-			h.Logger.Error("Failed to sync global commands: ", err)
-			return
-		}
-		h.Logger.Infof("Synced %d global commands", len(commands))
-		return
+func (h *Handler) AddDMMessageDeletes(dmMessageDeletes ...DMMessageDelete) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Static.DMMessageDelete = append(h.Static.DMMessageDelete, dmMessageDeletes...)
+}
+
+func (h *Handler) dmMessageDeletes() []DMMessageDelete {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return snapshot(h.Static.DMMessageDelete, h.DMMessageDelete)
+}
+
+func (h *Handler) AddEvent(events ...Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Event = append(h.Event, events...)
+}
+
+// IDを付けて一時的なハンダラを登録し、削除する関数を返す
+// IDがnilの場合は新しく生成する
+func (h *Handler) AddTemporaryEvent(event Event) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if event.ID == nil {
+		id := uuid.New()
+		event.ID = &id
 	}
+	h.EventMap[*event.ID] = event
+	return func() {
+		h.RemoveEvent(*event.ID)
+	}
+}
 
-	for _, guildID := range guildIDs {
-		if _, err := client.Rest().SetGuildCommands(client.ApplicationID(), guildID, commands); err != nil {
-			h.Logger.Errorf("Failed to sync commands for guild %d: %s", guildID, err)
-			continue
-		}
-		h.Logger.Infof("Synced %d commands for guild %s", len(commands), guildID)
+func (h *Handler) RemoveEvent(id uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.EventMap, id)
+}
+
+func (h *Handler) events() []Event {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return snapshot(h.Event, h.EventMap)
+}
+
+func (h *Handler) AddReady(ready func(*events.Ready)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Ready = append(h.Ready, ready)
+}
+
+func (h *Handler) handleReady(e *events.Ready) {
+	h.mu.RLock()
+	ready := append([]func(*events.Ready){}, h.Ready...)
+	h.mu.RUnlock()
+	for _, v := range ready {
+		h.invoke("ready handler", e, func() error {
+			v(e)
+			return nil
+		})
 	}
 }
 
+// 静的に登録されたものとUUIDで登録されたものを一つのスライスにまとめる
+func snapshot[T any](static []T, m map[uuid.UUID]T) []T {
+	s := make([]T, 0, len(static)+len(m))
+	s = append(s, static...)
+	for _, v := range m {
+		s = append(s, v)
+	}
+	return s
+}
+
 func (h *Handler) OnEvent(event bot.Event) {
-	if h.ASync {
+	h.dispatchMu.RLock()
+	if h.shutdown {
+		h.dispatchMu.RUnlock()
+		h.Logger.Debugf("停止中のためイベントを破棄 %T", event)
+		return
+	}
+	h.inFlight.Add(1)
+	d := h.dispatcher
+	h.dispatchMu.RUnlock()
+
+	// 待っているハンダラがワーカーを塞いでいても届くようにキューに入れる前に渡す
+	// 待っている側が受け取ったインタラクションには待っている側が応答するので、ハンダラには渡さない
+	if h.notifyWaiters(event) && isInteractionEvent(event) {
+		h.inFlight.Done()
+		return
+	}
+	switch {
+	case d != nil:
+		d.dispatch(event)
+	case h.ASync:
 		go func() {
-			if !h.IsDebug {
-				defer func() {
-					if err := recover(); err != nil {
-						h.Logger.Errorf("panic: %s", err)
-					}
-				}()
-			}
-			h.onEvent(event)
+			defer h.inFlight.Done()
+			h.dispatch(event)
 		}()
-	} else {
-		h.onEvent(event)
+	default:
+		defer h.inFlight.Done()
+		h.dispatch(event)
 	}
 }
 
+func (h *Handler) dispatch(event bot.Event) {
+	h.invoke(fmt.Sprintf("dispatch of %T", event), event, func() error {
+		h.onEvent(event)
+		return nil
+	})
+}
+
 func (h *Handler) onEvent(event bot.Event) {
+	ctx, cancel := h.eventContext()
+	defer cancel()
 	switch e := event.(type) {
 	case *events.ApplicationCommandInteractionCreate:
 		h.handleCommand(e)
@@ -251,25 +559,21 @@ func (h *Handler) onEvent(event bot.Event) {
 	case *events.ModalSubmitInteractionCreate:
 		h.handleModal(e)
 	case *events.GuildMessageCreate:
-		h.handleMessage(e)
+		h.handleMessage(ctx, e)
 	case *events.GuildMessageDelete:
-		h.handleMessageDelete(e)
+		h.handleMessageDelete(ctx, e)
 	case *events.GuildMessageUpdate:
-		h.handleMessageUpdate(e)
+		h.handleMessageUpdate(ctx, e)
+	case *events.DMMessageCreate:
+		h.handleDMMessage(ctx, e)
+	case *events.DMMessageUpdate:
+		h.handleDMMessageUpdate(ctx, e)
+	case *events.DMMessageDelete:
+		h.handleDMMessageDelete(ctx, e)
 	case *events.Ready:
 		h.handleReady(e)
-	case *events.GuildMemberJoin:
-		h.MemberJoin.handleEvent(e)
-	case *events.GuildMemberLeave:
-		h.MemberLeave.handleEvent(e)
-	case *events.GuildMessageReactionAdd:
-		h.MessageReactionAdd.handleEvent(e)
-	case *events.GuildMessageReactionRemove:
-		h.MessageReactionRemove.handleEvent(e)
-	case *events.GuildMessageReactionRemoveAll:
-		h.MessageReactionRemoveAll.handleEvent(e)
-	case *events.GuildMessageReactionRemoveEmoji:
-		h.MessageReactionRemoveEmoji.handleEvent(e)
+	default:
+		h.EventHandlers.dispatchEvent(ctx, event)
 	}
-	h.handleEvent(event)
+	h.handleEvent(ctx, event)
 }
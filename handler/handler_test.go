@@ -0,0 +1,312 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/log"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/google/uuid"
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+)
+
+const (
+	workers    = 8
+	iterations = 200
+)
+
+func newGuildMessageCreate(channelID snowflake.ID) *events.GuildMessageCreate {
+	return &events.GuildMessageCreate{
+		GenericGuildMessage: &events.GenericGuildMessage{
+			GenericEvent: events.NewGenericEvent(nil, 0, 0),
+			ChannelID:    channelID,
+			Message:      discord.Message{ChannelID: channelID},
+		},
+	}
+}
+
+func newDMMessageCreate(channelID, authorID snowflake.ID) *events.DMMessageCreate {
+	return &events.DMMessageCreate{
+		GenericDMMessage: &events.GenericDMMessage{
+			GenericEvent: events.NewGenericEvent(nil, 0, 0),
+			ChannelID:    channelID,
+			Message:      discord.Message{ChannelID: channelID, Author: discord.User{ID: authorID}},
+		},
+	}
+}
+
+func TestConcurrentComponentRegistration(t *testing.T) {
+	h := New(log.Default())
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				remove := h.AddComponent(Component{Name: fmt.Sprintf("component-%d-%d", i, j)})
+				remove()
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				h.component(fmt.Sprintf("component-%d-%d", i, j))
+			}
+		}(i)
+	}
+	wg.Wait()
+	if len(h.Components) != 0 {
+		t.Errorf("expected no components left, got %d", len(h.Components))
+	}
+}
+
+func TestConcurrentCommandAndModalRegistration(t *testing.T) {
+	h := New(log.Default())
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				name := fmt.Sprintf("name-%d-%d", i, j)
+				h.AddCommands(Command{Create: discord.SlashCommandCreate{Name: name}})
+				remove := h.AddModal(Modal{Name: name})
+				h.RemoveCommand(name)
+				remove()
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				name := fmt.Sprintf("name-%d-%d", i, j)
+				h.command(name)
+				h.modal(name)
+				h.commands()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if len(h.Commands) != 0 || len(h.Modals) != 0 {
+		t.Errorf("expected no commands or modals left, got %d, %d", len(h.Commands), len(h.Modals))
+	}
+}
+
+func TestConcurrentMessageRegistrationDuringDispatch(t *testing.T) {
+	h := New(log.Default())
+	var called atomic.Int64
+	h.AddMessages(Message{
+		Handler: func(event *events.GuildMessageCreate) error {
+			called.Add(1)
+			return nil
+		},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				id := uuid.New()
+				remove := h.AddMessage(Message{
+					UUID: &id,
+					Handler: func(event *events.GuildMessageCreate) error {
+						return nil
+					},
+				})
+				h.AddExclude(snowflake.ID(j + 1000))
+				remove()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				h.OnEvent(newGuildMessageCreate(snowflake.ID(j)))
+			}
+		}()
+	}
+	wg.Wait()
+	if called.Load() != workers*iterations {
+		t.Errorf("expected static handler to be called %d times, got %d", workers*iterations, called.Load())
+	}
+	if len(h.Message) != 0 {
+		t.Errorf("expected no temporary message handlers left, got %d", len(h.Message))
+	}
+}
+
+func TestRemoveMessageFromHandler(t *testing.T) {
+	h := New(log.Default())
+	id := uuid.New()
+	var (
+		remove func()
+		called atomic.Int64
+	)
+	remove = h.AddMessage(Message{
+		UUID: &id,
+		Handler: func(event *events.GuildMessageCreate) error {
+			called.Add(1)
+			remove()
+			return nil
+		},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.OnEvent(newGuildMessageCreate(1))
+		}()
+	}
+	wg.Wait()
+	if called.Load() == 0 {
+		t.Error("expected temporary handler to be called")
+	}
+	if len(h.Message) != 0 {
+		t.Errorf("expected temporary handler to be removed, got %d", len(h.Message))
+	}
+}
+
+func TestConcurrentGenericsRegistrationDuringDispatch(t *testing.T) {
+	type testEvent struct{ n int }
+	list := newGenericsList[testEvent](New(log.Default()))
+	var called atomic.Int64
+	list.Adds(Generics[testEvent]{
+		Handler: func(event *testEvent) error {
+			called.Add(1)
+			return nil
+		},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				id := uuid.New()
+				remove := list.Add(Generics[testEvent]{
+					ID: &id,
+					Handler: func(event *testEvent) error {
+						return nil
+					},
+				})
+				remove()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				list.handleEvent(context.Background(), &testEvent{n: j})
+			}
+		}()
+	}
+	wg.Wait()
+	if called.Load() != workers*iterations {
+		t.Errorf("expected static handler to be called %d times, got %d", workers*iterations, called.Load())
+	}
+	if len(list.Map) != 0 {
+		t.Errorf("expected no temporary handlers left, got %d", len(list.Map))
+	}
+}
+
+func TestDMMessage(t *testing.T) {
+	h := New(log.Default())
+	var guildCalled bool
+	h.AddMessages(Message{
+		Handler: func(event *events.GuildMessageCreate) error {
+			guildCalled = true
+			return nil
+		},
+	})
+	id := uuid.New()
+	authorID := snowflake.ID(2)
+	var called int
+	remove := h.AddDMMessage(DMMessage{
+		UUID:     &id,
+		AuthorID: &authorID,
+		Handler: func(event *events.DMMessageCreate) error {
+			called++
+			return nil
+		},
+	})
+
+	h.OnEvent(newDMMessageCreate(1, 3))
+	h.OnEvent(newDMMessageCreate(1, authorID))
+	remove()
+	h.OnEvent(newDMMessageCreate(1, authorID))
+	if called != 1 {
+		t.Errorf("expected DM handler to be called once, got %d", called)
+	}
+	if guildCalled {
+		t.Error("expected guild message handler not to be called for DMs")
+	}
+}
+
+// ASyncでイベントを処理している間にハンダラを追加、削除する
+// 各ハンダラの一覧を複製する処理が並行に動くので-raceで実行すること
+func TestConcurrentRegistrationDuringAsyncDispatch(t *testing.T) {
+	h := New(log.Default())
+	h.ASync = true
+	var messages, reactions atomic.Int64
+	h.AddMessages(Message{
+		Handler: func(event *events.GuildMessageCreate) error {
+			messages.Add(1)
+			return nil
+		},
+	})
+	h.MessageReactionAdd.Adds(Generics[events.GuildMessageReactionAdd]{
+		Handler: func(event *events.GuildMessageReactionAdd) error {
+			reactions.Add(1)
+			return nil
+		},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				messageID, reactionID := uuid.New(), uuid.New()
+				removeMessage := h.AddMessage(Message{UUID: &messageID, Handler: func(*events.GuildMessageCreate) error { return nil }})
+				removeEvent := h.AddTemporaryEvent(Event{Handler: func(bot.Event) error { return nil }})
+				removeReaction := h.MessageReactionAdd.Add(Generics[events.GuildMessageReactionAdd]{
+					ID:      &reactionID,
+					Handler: func(*events.GuildMessageReactionAdd) error { return nil },
+				})
+				removeComponent := h.AddComponent(Component{Name: fmt.Sprintf("component-%d-%d", i, j)})
+				removeMessage()
+				removeEvent()
+				removeReaction()
+				removeComponent()
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				h.OnEvent(newGuildMessageCreate(snowflake.ID(j)))
+				h.OnEvent(&events.GuildMessageReactionAdd{})
+			}
+		}()
+	}
+	wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if messages.Load() != workers*iterations || reactions.Load() != workers*iterations {
+		t.Errorf("expected %d messages and reactions, got %d and %d", workers*iterations, messages.Load(), reactions.Load())
+	}
+	if len(h.Message) != 0 || len(h.EventMap) != 0 || len(h.Components) != 0 || len(h.MessageReactionAdd.Map) != 0 {
+		t.Errorf("expected no temporary handlers left, got %d, %d, %d, %d", len(h.Message), len(h.EventMap), len(h.Components), len(h.MessageReactionAdd.Map))
+	}
+}
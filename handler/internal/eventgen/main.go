@@ -0,0 +1,120 @@
+// ゲートウェイイベントのハンダラ一覧とディスパッチを生成する
+//
+//	go generate ./handler
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"log"
+	"os"
+	"text/template"
+)
+
+type event struct {
+	// EventHandlersのフィールド名
+	Field string
+	// eventsパッケージの型名
+	Type string
+}
+
+// イベントを追加する場合はここに追加して go generate を実行する
+var eventList = []event{
+	{"MemberJoin", "GuildMemberJoin"},
+	{"MemberUpdate", "GuildMemberUpdate"},
+	{"MemberLeave", "GuildMemberLeave"},
+
+	{"MessageReactionAdd", "GuildMessageReactionAdd"},
+	{"MessageReactionRemove", "GuildMessageReactionRemove"},
+	{"MessageReactionRemoveAll", "GuildMessageReactionRemoveAll"},
+	{"MessageReactionRemoveEmoji", "GuildMessageReactionRemoveEmoji"},
+
+	{"VoiceStateUpdate", "GuildVoiceStateUpdate"},
+	{"VoiceJoin", "GuildVoiceJoin"},
+	{"VoiceMove", "GuildVoiceMove"},
+	{"VoiceLeave", "GuildVoiceLeave"},
+
+	{"ChannelCreate", "GuildChannelCreate"},
+	{"ChannelUpdate", "GuildChannelUpdate"},
+	{"ChannelDelete", "GuildChannelDelete"},
+
+	{"ThreadCreate", "ThreadCreate"},
+	{"ThreadUpdate", "ThreadUpdate"},
+	{"ThreadDelete", "ThreadDelete"},
+
+	{"RoleCreate", "RoleCreate"},
+	{"RoleUpdate", "RoleUpdate"},
+	{"RoleDelete", "RoleDelete"},
+
+	{"GuildJoin", "GuildJoin"},
+	{"GuildLeave", "GuildLeave"},
+
+	{"ScheduledEventCreate", "GuildScheduledEventCreate"},
+	{"ScheduledEventUpdate", "GuildScheduledEventUpdate"},
+	{"ScheduledEventDelete", "GuildScheduledEventDelete"},
+	{"ScheduledEventUserAdd", "GuildScheduledEventUserAdd"},
+	{"ScheduledEventUserRemove", "GuildScheduledEventUserRemove"},
+
+	{"AutoModerationRuleCreate", "AutoModerationRuleCreate"},
+	{"AutoModerationRuleUpdate", "AutoModerationRuleUpdate"},
+	{"AutoModerationRuleDelete", "AutoModerationRuleDelete"},
+	{"AutoModerationActionExecution", "AutoModerationActionExecution"},
+
+	{"PresenceUpdate", "PresenceUpdate"},
+
+	{"InviteCreate", "InviteCreate"},
+	{"InviteDelete", "InviteDelete"},
+}
+
+var tmpl = template.Must(template.New("events").Parse(`// Code generated by eventgen; DO NOT EDIT.
+
+package handler
+
+import (
+	"context"
+
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/events"
+)
+
+// ゲートウェイイベントごとのハンダラ
+type EventHandlers struct {
+{{- range .}}
+	{{.Field}} *genericsList[events.{{.Type}}]
+{{- end}}
+}
+
+func (l *EventHandlers) init(h *Handler) {
+{{- range .}}
+	l.{{.Field}} = newGenericsList[events.{{.Type}}](h)
+{{- end}}
+}
+
+// イベントを対応するハンダラに渡す
+// 対応するハンダラがない場合はfalseを返す
+func (l *EventHandlers) dispatchEvent(ctx context.Context, event bot.Event) bool {
+	switch e := event.(type) {
+{{- range .}}
+	case *events.{{.Type}}:
+		l.{{.Field}}.handleEvent(ctx, e)
+{{- end}}
+	default:
+		return false
+	}
+	return true
+}
+`))
+
+func main() {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, eventList); err != nil {
+		log.Fatal(err)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("events_gen.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
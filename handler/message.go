@@ -1,37 +1,58 @@
 package handler
 
 import (
+	"context"
+
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/google/uuid"
 	"github.com/sabafly/sabafly-disgo/events"
 )
 
 type (
-	MessageHandler func(event *events.GuildMessageCreate) error
+	MessageHandler        func(event *events.GuildMessageCreate) error
+	MessageContextHandler func(ctx context.Context, event *events.GuildMessageCreate) error
 )
 
+func (f MessageHandler) WithContext() MessageContextHandler {
+	return func(_ context.Context, event *events.GuildMessageCreate) error {
+		return f(event)
+	}
+}
+
 type Message struct {
 	UUID      *uuid.UUID
 	ChannelID *snowflake.ID
 	AuthorID  *snowflake.ID
 	Check     Check[*events.GuildMessageCreate]
 	Handler   MessageHandler
+
+	ContextHandler MessageContextHandler
+}
+
+func (m Message) handler() MessageContextHandler {
+	if m.ContextHandler != nil {
+		return m.ContextHandler
+	}
+	if m.Handler != nil {
+		return m.Handler.WithContext()
+	}
+	return nil
 }
 
-func (h *Handler) handleMessage(event *events.GuildMessageCreate) {
-	if _, ok := h.ExcludeID[event.ChannelID]; ok {
+func (h *Handler) handleMessage(ctx context.Context, event *events.GuildMessageCreate) {
+	if h.isExcluded(event.ChannelID) {
 		return
 	}
 	h.Logger.Debugf("メッセージ作成 %d", event.ChannelID)
-	for _, m := range h.Static.Message {
-		h.run_message(m, event)
-	}
-	for _, m := range h.Message {
-		h.run_message(m, event)
+	for _, m := range h.messages() {
+		h.invoke("message handler", event, func() error {
+			h.run_message(ctx, m, event)
+			return nil
+		})
 	}
 }
 
-func (h *Handler) run_message(m Message, event *events.GuildMessageCreate) {
+func (h *Handler) run_message(ctx context.Context, m Message, event *events.GuildMessageCreate) {
 	if m.ChannelID != nil && *m.ChannelID != event.ChannelID {
 		h.Logger.Debug("チャンネルが違います")
 		return
@@ -40,10 +61,14 @@ func (h *Handler) run_message(m Message, event *events.GuildMessageCreate) {
 		h.Logger.Debugf("送信者が違います %d %d", *m.AuthorID, event.Message.Author.ID)
 		return
 	}
-	if m.Check != nil && !m.Check(event) {
+	if !m.Check.Run(event).Allowed {
+		return
+	}
+	handler := m.handler()
+	if handler == nil {
 		return
 	}
-	if err := m.Handler(event); err != nil {
+	if err := handler(ctx, event); err != nil {
 		h.Logger.Errorf("Failed to handle message \"%d\" in \"%s\", %s: %s", event.MessageID, event.GuildID, event.ChannelID, err.Error())
 	}
 }
@@ -1,37 +1,58 @@
 package handler
 
 import (
+	"context"
+
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/google/uuid"
 	"github.com/sabafly/sabafly-disgo/events"
 )
 
 type (
-	MessageDeleteHandler func(event *events.GuildMessageDelete) error
+	MessageDeleteHandler        func(event *events.GuildMessageDelete) error
+	MessageDeleteContextHandler func(ctx context.Context, event *events.GuildMessageDelete) error
 )
 
+func (f MessageDeleteHandler) WithContext() MessageDeleteContextHandler {
+	return func(_ context.Context, event *events.GuildMessageDelete) error {
+		return f(event)
+	}
+}
+
 type MessageDelete struct {
 	UUID      *uuid.UUID
 	ChannelID *snowflake.ID
 	AuthorID  *snowflake.ID
 	Check     Check[*events.GuildMessageDelete]
 	Handler   MessageDeleteHandler
+
+	ContextHandler MessageDeleteContextHandler
+}
+
+func (m MessageDelete) handler() MessageDeleteContextHandler {
+	if m.ContextHandler != nil {
+		return m.ContextHandler
+	}
+	if m.Handler != nil {
+		return m.Handler.WithContext()
+	}
+	return nil
 }
 
-func (h *Handler) handleMessageDelete(event *events.GuildMessageDelete) {
-	if _, ok := h.ExcludeID[event.ChannelID]; ok {
+func (h *Handler) handleMessageDelete(ctx context.Context, event *events.GuildMessageDelete) {
+	if h.isExcluded(event.ChannelID) {
 		return
 	}
 	h.Logger.Debugf("メッセージ作成 %d", event.ChannelID)
-	for _, m := range h.Static.MessageDelete {
-		h.run_message_delete(m, event)
-	}
-	for _, m := range h.MessageDelete {
-		h.run_message_delete(m, event)
+	for _, m := range h.messageDeletes() {
+		h.invoke("message delete handler", event, func() error {
+			h.run_message_delete(ctx, m, event)
+			return nil
+		})
 	}
 }
 
-func (h *Handler) run_message_delete(m MessageDelete, event *events.GuildMessageDelete) {
+func (h *Handler) run_message_delete(ctx context.Context, m MessageDelete, event *events.GuildMessageDelete) {
 	if m.ChannelID != nil && *m.ChannelID != event.ChannelID {
 		h.Logger.Debug("チャンネルが違います")
 		return
@@ -40,10 +61,14 @@ func (h *Handler) run_message_delete(m MessageDelete, event *events.GuildMessage
 		h.Logger.Debugf("送信者が違います %d %d", *m.AuthorID, event.Message.Author.ID)
 		return
 	}
-	if m.Check != nil && !m.Check(event) {
+	if !m.Check.Run(event).Allowed {
+		return
+	}
+	handler := m.handler()
+	if handler == nil {
 		return
 	}
-	if err := m.Handler(event); err != nil {
+	if err := handler(ctx, event); err != nil {
 		h.Logger.Errorf("Failed to handle message \"%d\" in \"%s\", %s: %s", event.MessageID, event.GuildID, event.ChannelID, err.Error())
 	}
 }
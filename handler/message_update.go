@@ -1,37 +1,58 @@
 package handler
 
 import (
+	"context"
+
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/google/uuid"
 	"github.com/sabafly/sabafly-disgo/events"
 )
 
 type (
-	MessageUpdateHandler func(event *events.GuildMessageUpdate) error
+	MessageUpdateHandler        func(event *events.GuildMessageUpdate) error
+	MessageUpdateContextHandler func(ctx context.Context, event *events.GuildMessageUpdate) error
 )
 
+func (f MessageUpdateHandler) WithContext() MessageUpdateContextHandler {
+	return func(_ context.Context, event *events.GuildMessageUpdate) error {
+		return f(event)
+	}
+}
+
 type MessageUpdate struct {
 	UUID      *uuid.UUID
 	ChannelID *snowflake.ID
 	AuthorID  *snowflake.ID
 	Check     Check[*events.GuildMessageUpdate]
 	Handler   MessageUpdateHandler
+
+	ContextHandler MessageUpdateContextHandler
+}
+
+func (m MessageUpdate) handler() MessageUpdateContextHandler {
+	if m.ContextHandler != nil {
+		return m.ContextHandler
+	}
+	if m.Handler != nil {
+		return m.Handler.WithContext()
+	}
+	return nil
 }
 
-func (h *Handler) handleMessageUpdate(event *events.GuildMessageUpdate) {
-	if _, ok := h.ExcludeID[event.ChannelID]; ok {
+func (h *Handler) handleMessageUpdate(ctx context.Context, event *events.GuildMessageUpdate) {
+	if h.isExcluded(event.ChannelID) {
 		return
 	}
 	h.Logger.Debugf("メッセージ作成 %d", event.ChannelID)
-	for _, m := range h.Static.MessageUpdate {
-		h.run_message_update(m, event)
-	}
-	for _, m := range h.MessageUpdate {
-		h.run_message_update(m, event)
+	for _, m := range h.messageUpdates() {
+		h.invoke("message update handler", event, func() error {
+			h.run_message_update(ctx, m, event)
+			return nil
+		})
 	}
 }
 
-func (h *Handler) run_message_update(m MessageUpdate, event *events.GuildMessageUpdate) {
+func (h *Handler) run_message_update(ctx context.Context, m MessageUpdate, event *events.GuildMessageUpdate) {
 	if m.ChannelID != nil && *m.ChannelID != event.ChannelID {
 		h.Logger.Debug("チャンネルが違います")
 		return
@@ -40,10 +61,14 @@ func (h *Handler) run_message_update(m MessageUpdate, event *events.GuildMessage
 		h.Logger.Debugf("送信者が違います %d %d", *m.AuthorID, event.Message.Author.ID)
 		return
 	}
-	if m.Check != nil && !m.Check(event) {
+	if !m.Check.Run(event).Allowed {
+		return
+	}
+	handler := m.handler()
+	if handler == nil {
 		return
 	}
-	if err := m.Handler(event); err != nil {
+	if err := handler(ctx, event); err != nil {
 		h.Logger.Errorf("Failed to handle message \"%d\" in \"%s\", %s: %s", event.MessageID, event.GuildID, event.ChannelID, err.Error())
 	}
 }
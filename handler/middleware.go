@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/disgoorg/log"
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+)
+
+type (
+	HandlerFunc func(ctx context.Context, event bot.Event) error
+	// 次のハンダラを包むミドルウェア
+	// nextを呼ばないことで処理を中断できる
+	Middleware func(next HandlerFunc) HandlerFunc
+)
+
+// 全てのインタラクションに適用するミドルウェアを追加する
+func (h *Handler) Use(middlewares ...Middleware) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Middlewares = append(h.Middlewares, middlewares...)
+}
+
+func (h *Handler) middlewares() []Middleware {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.IsLogEvent {
+		return append([]Middleware{Logging(h.Logger)}, h.Middlewares...)
+	}
+	return append([]Middleware{}, h.Middlewares...)
+}
+
+// 先に渡されたミドルウェアほど外側になるようにハンダラを包む
+func chain(handler HandlerFunc, middlewares ...[]Middleware) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		for j := len(middlewares[i]) - 1; j >= 0; j-- {
+			handler = middlewares[i][j](handler)
+		}
+	}
+	return handler
+}
+
+func typed[T bot.Event](event T, handler func(ctx context.Context, event T) error) HandlerFunc {
+	return func(ctx context.Context, e bot.Event) error {
+		if v, ok := e.(T); ok {
+			return handler(ctx, v)
+		}
+		return handler(ctx, event)
+	}
+}
+
+// パニックを回復してエラーとして返す
+// パニックはHandlerのPanicReportersに報告する
+func (h *Handler) Recover() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, event bot.Event) error {
+			return h.invoke(fmt.Sprintf("middleware for %T", event), event, func() error { return next(ctx, event) })
+		}
+	}
+}
+
+// インタラクションの使用をログに出力する
+// HandlerのIsLogEventがtrueの場合は全てのインタラクションの最も外側に追加される
+func Logging(logger log.Logger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, event bot.Event) error {
+			logEvent(logger, event)
+			return next(ctx, event)
+		}
+	}
+}
+
+// ハンダラの処理時間をログに出力する
+func Timing(logger log.Logger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, event bot.Event) error {
+			start := time.Now()
+			err := next(ctx, event)
+			logger.Debugf("handled %T in %s", event, time.Since(start))
+			return err
+		}
+	}
+}
+
+func logEvent(logger log.Logger, event bot.Event) {
+	switch e := event.(type) {
+	case *events.ApplicationCommandInteractionCreate:
+		switch d := e.Data.(type) {
+		case discord.SlashCommandInteractionData:
+			logger.Infof("%s(%s) used %s command type %d", e.User().Tag(), e.User().ID, d.CommandPath(), d.Type())
+		case discord.UserCommandInteractionData:
+			logger.Infof("%s(%s) used %s command target %s type %d",
+				e.User().Tag(), e.User().ID, d.CommandName(), d.TargetID(), d.Type(),
+			)
+		case discord.MessageCommandInteractionData:
+			logger.Infof("%s(%s) used %s command target %s type %d",
+				e.User().Tag(), e.User().ID, d.CommandName(), d.TargetMessage().JumpURL(), d.Type(),
+			)
+		default:
+			logger.Infof("%s(%s) used %s command type %d", e.User().Tag(), e.User().ID, d.CommandName(), d.Type())
+		}
+	case *events.AutocompleteInteractionCreate:
+		logger.Debugf("%s(%s) used %s autocomplete", e.User().Tag(), e.User().ID, e.Data.CommandPath())
+	case *events.ComponentInteractionCreate:
+		logger.Infof("%s(%s) used %s component", e.User().Tag(), e.User().ID, e.Data.CustomID())
+	case *events.ModalSubmitInteractionCreate:
+		logger.Infof("%s(%s) used %s modal", e.User().Tag(), e.User().ID, e.Data.CustomID)
+	}
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/disgoorg/log"
+	"github.com/sabafly/sabafly-disgo/bot"
+)
+
+func TestRecoverMiddleware(t *testing.T) {
+	h := New(log.Default())
+	var reports []*PanicReport
+	h.AddPanicReporter(func(report *PanicReport) {
+		reports = append(reports, report)
+	})
+	// Recoverより外側のミドルウェアはパニックをエラーとして受け取る
+	var observed error
+	observe := func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, event bot.Event) error {
+			observed = next(ctx, event)
+			return observed
+		}
+	}
+	run := chain(func(context.Context, bot.Event) error { panic("boom") }, []Middleware{observe, h.Recover()})
+	err := run(context.Background(), nil)
+	if _, ok := err.(*PanicReport); !ok || observed != err {
+		t.Fatalf("expected panic report error, got %v observed %v", err, observed)
+	}
+	if len(reports) != 1 || reports[0].Value != "boom" {
+		t.Errorf("unexpected reports %+v", reports)
+	}
+}
+
+func TestIsLogEventMiddleware(t *testing.T) {
+	h := New(log.Default())
+	h.Use(Timing(h.Logger))
+	if len(h.middlewares()) != 1 {
+		t.Fatalf("expected only the added middleware, got %d", len(h.middlewares()))
+	}
+	h.IsLogEvent = true
+	if len(h.middlewares()) != 2 {
+		t.Fatalf("expected logging to be added as a middleware, got %d", len(h.middlewares()))
+	}
+	if len(h.Middlewares) != 1 {
+		t.Errorf("expected registered middlewares to be unchanged, got %d", len(h.Middlewares))
+	}
+}
@@ -1,12 +1,25 @@
 package handler
 
 import (
-	"strings"
+	"context"
+	"fmt"
 
+	"github.com/sabafly/sabafly-lib/v2/handler/customid"
+
+	"github.com/sabafly/sabafly-disgo/discord"
 	"github.com/sabafly/sabafly-disgo/events"
 )
 
-type ModalHandler func(event *events.ModalSubmitInteractionCreate) error
+type (
+	ModalHandler        func(event *events.ModalSubmitInteractionCreate) error
+	ModalContextHandler func(ctx context.Context, event *events.ModalSubmitInteractionCreate) error
+)
+
+func (f ModalHandler) WithContext() ModalContextHandler {
+	return func(_ context.Context, event *events.ModalSubmitInteractionCreate) error {
+		return f(event)
+	}
+}
 
 type Modal struct {
 	Name      string
@@ -14,44 +27,79 @@ type Modal struct {
 	Checks    map[string]Check[*events.ModalSubmitInteractionCreate]
 	Handler   map[string]ModalHandler
 	Ephemeral map[string]bool
+	// trueの場合は遅延応答にDeferUpdateMessageを使う
+	DeferUpdate map[string]bool
+	Middlewares []Middleware
+	// サブハンダラごとのクールダウン
+	Cooldowns map[string]Cooldown
+	// ハンダラが返したエラーを処理する
+	OnError ErrorHandler
+
+	ContextHandler map[string]ModalContextHandler
 }
 
-func (h *Handler) handleModal(event *events.ModalSubmitInteractionCreate) {
-	if h.IsLogEvent {
-		h.Logger.Infof("%s(%s) used %d modal", event.Member().User.Tag(), event.Member().User.ID, event.Data.CustomID)
+func (m Modal) handler(name string) (ModalContextHandler, bool) {
+	if handler, ok := m.ContextHandler[name]; ok {
+		return handler, true
+	}
+	if handler, ok := m.Handler[name]; ok {
+		return handler.WithContext(), true
 	}
+	return nil, false
+}
+
+func (h *Handler) handleModal(event *events.ModalSubmitInteractionCreate) {
+	e := *event
+	tracker := h.newAckTracker(event.Client(), event, event.Respond)
+	e.Respond = tracker.respond
+	event = &e
+
 	customID := event.Data.CustomID
 	h.Logger.Debugf("モーダル提出インタラクション呼び出し %s", customID)
-	if !strings.HasPrefix(customID, "handler:") {
+	if !customid.IsHandlerID(customID) {
 		return
 	}
-
-	var subName string
-	if strings.Count(customID, ":") >= 2 {
-		subName = strings.Split(customID, ":")[2]
+	id, err := customid.Decode(customID, h.CustomIDKey)
+	if err != nil {
+		h.Logger.Warnf("不正なカスタムID %s: %s", customID, err)
+		return
 	}
+	subName := id.Sub
 
-	modalName := strings.Split(customID, ":")[1]
-	modal, ok := h.Modals[modalName]
-	if !ok || modal.Handler == nil {
+	modalName := id.Name
+	modal, ok := h.modal(modalName)
+	if !ok || (modal.Handler == nil && modal.ContextHandler == nil) {
 		h.Logger.Errorf("No modal handler for \"%s\" found", modalName)
 	}
 
-	if modal.Check != nil && !modal.Check(event) {
+	if !checkInteraction(h, event, modal.Check, modal.Checks[subName]) {
 		return
 	}
 
-	if check, ok := modal.Checks[modalName]; ok && !check(event) {
+	handler, ok := modal.handler(subName)
+	if !ok {
+		h.Logger.Debugf("不明なハンダラ %s", subName)
+		return
+	}
+	if !h.takeCooldown("modal", modalName, subName, modal.Cooldowns, event) {
 		return
 	}
 
-	handler, ok := modal.Handler[subName]
-	if !ok {
-		h.Logger.Debugf("不明なハンダラ %s", subName)
+	ctx, cancel := h.interactionContext(event)
+	defer cancel()
+	ctx = withAckTracker(ctx, tracker)
+	ctx = withCustomID(ctx, id)
+	if ctx, ok = h.loadState(ctx, id, event); !ok {
 		return
 	}
-	defer deferUpdateInteraction(event, modal.Ephemeral != nil && modal.Ephemeral[subName])
-	if err := handler(event); err != nil {
-		h.Logger.Errorf("Failed to handle modal interaction for \"%s\" : %s", modalName, err)
+	if modal.DeferUpdate != nil && modal.DeferUpdate[subName] {
+		tracker.autoDefer(h.autoDeferTimeout(), discord.InteractionResponseTypeDeferredUpdateMessage, nil)
+	} else {
+		tracker.autoDefer(h.autoDeferTimeout(), discord.InteractionResponseTypeDeferredCreateMessage, deferCreateMessageData(modal.Ephemeral != nil && modal.Ephemeral[subName]))
+	}
+	run := chain(typed(event, handler), h.middlewares(), modal.Middlewares)
+	source := fmt.Sprintf("modal \"%s\" with handler \"%s\"", modalName, subName)
+	if err := h.invoke(source, event, func() error { return run(ctx, event) }); err != nil {
+		h.handleError(ctx, event, event.Locale(), tracker, modal.OnError, source, err)
 	}
 }
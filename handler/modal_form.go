@@ -0,0 +1,464 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/disgoorg/json"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+	"github.com/sabafly/sabafly-lib/v2/handler/customid"
+	"github.com/sabafly/sabafly-lib/v2/translate"
+)
+
+type (
+	TypedModalHandler[T any] func(ctx context.Context, event *events.ModalSubmitInteractionCreate, fields T) error
+
+	// デコード後に呼び出される検証
+	ModalValidator interface {
+		ValidateModal() error
+	}
+)
+
+// モーダルに置ける入力欄の最大数
+const MaxModalFields = 5
+
+const (
+	modalFormSubmit = "submit"
+	modalFormRetry  = "retry"
+)
+
+var modalFieldsCache sync.Map
+
+type modalField struct {
+	index       []int
+	typ         reflect.Type
+	pointer     bool
+	name        string
+	label       string
+	placeholder string
+	localize    string
+	required    bool
+	paragraph   bool
+	min         *int
+	max         *int
+}
+
+func modalFieldsOf(t reflect.Type) ([]modalField, error) {
+	if v, ok := modalFieldsCache.Load(t); ok {
+		return v.([]modalField), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("modal fields must be a struct, got %s", t)
+	}
+	var fields []modalField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("field")
+		if !ok || tag == "-" || !sf.IsExported() {
+			continue
+		}
+		field, err := parseModalField(sf, tag)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) > MaxModalFields {
+		return nil, fmt.Errorf("modal can have at most %d fields, got %d", MaxModalFields, len(fields))
+	}
+	modalFieldsCache.Store(t, fields)
+	return fields, nil
+}
+
+func parseModalField(sf reflect.StructField, tag string) (modalField, error) {
+	field := modalField{
+		index:       sf.Index,
+		typ:         sf.Type,
+		label:       sf.Tag.Get("label"),
+		placeholder: sf.Tag.Get("placeholder"),
+		localize:    sf.Tag.Get("localize"),
+	}
+	if field.typ.Kind() == reflect.Pointer {
+		field.pointer = true
+		field.typ = field.typ.Elem()
+	}
+	switch field.typ.Kind() {
+	case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+	default:
+		return field, fmt.Errorf("unsupported field type %s", field.typ)
+	}
+
+	parts := strings.Split(tag, ",")
+	field.name = parts[0]
+	if field.name == "" {
+		field.name = strings.ToLower(sf.Name)
+	}
+	if field.label == "" {
+		field.label = sf.Name
+	}
+	for _, part := range parts[1:] {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "required":
+			field.required = true
+		case "paragraph":
+			field.paragraph = true
+		case "min", "max":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return field, fmt.Errorf("invalid %s %q: %w", key, value, err)
+			}
+			if key == "min" {
+				field.min = &n
+			} else {
+				field.max = &n
+			}
+		default:
+			return field, fmt.Errorf("unknown field tag %q", key)
+		}
+	}
+	return field, nil
+}
+
+func (f modalField) isString() bool {
+	return f.typ.Kind() == reflect.String
+}
+
+// 入力欄を作成する
+// valueは入力済みの値
+func (f modalField) component(locale discord.Locale, value string) discord.TextInputComponent {
+	label, placeholder := f.label, f.placeholder
+	if f.localize != "" {
+		label = translate.Message(locale, f.localize+"_label", translate.WithFallback(label))
+		if placeholder != "" {
+			placeholder = translate.Message(locale, f.localize+"_placeholder", translate.WithFallback(placeholder))
+		}
+	}
+	component := discord.TextInputComponent{
+		CustomID:    f.name,
+		Style:       discord.TextInputStyleShort,
+		Label:       label,
+		Required:    f.required,
+		Placeholder: placeholder,
+		Value:       value,
+	}
+	if f.paragraph {
+		component.Style = discord.TextInputStyleParagraph
+	}
+	// 数値の最小と最大は値の範囲なので文字数には使わない
+	if f.isString() {
+		if f.min != nil {
+			component.MinLength = json.Ptr(*f.min)
+		}
+		if f.max != nil {
+			component.MaxLength = *f.max
+		}
+	}
+	return component
+}
+
+func (f modalField) decode(text string) (reflect.Value, error) {
+	var (
+		n   float64
+		v   reflect.Value
+		err error
+	)
+	switch f.typ.Kind() {
+	case reflect.String:
+		n = float64(utf8.RuneCountInString(text))
+		v = reflect.ValueOf(text)
+	case reflect.Float32, reflect.Float64:
+		var f64 float64
+		f64, err = strconv.ParseFloat(text, f.typ.Bits())
+		n = f64
+		v = reflect.ValueOf(f64)
+	default:
+		var i64 int64
+		i64, err = strconv.ParseInt(text, 10, f.typ.Bits())
+		n = float64(i64)
+		v = reflect.ValueOf(i64)
+	}
+	// フィールドの型に収まらない
+	if errors.Is(err, strconv.ErrRange) {
+		return reflect.Value{}, &OptionError{
+			Option: f.name,
+			Key:    OptionErrorOutOfRange,
+			Data:   map[string]any{"Option": f.label, "Min": f.min, "Max": f.max},
+			Err:    err,
+		}
+	}
+	if err != nil {
+		return reflect.Value{}, &OptionError{
+			Option: f.name,
+			Key:    OptionErrorInvalid,
+			Data:   map[string]any{"Option": f.label, "Error": err.Error()},
+			Err:    err,
+		}
+	}
+	if (f.min != nil && n < float64(*f.min)) || (f.max != nil && n > float64(*f.max)) {
+		return reflect.Value{}, &OptionError{
+			Option: f.name,
+			Key:    OptionErrorOutOfRange,
+			Data:   map[string]any{"Option": f.label, "Min": f.min, "Max": f.max},
+		}
+	}
+	return v.Convert(f.typ), nil
+}
+
+// 値を入力欄に表示する文字列にする
+func (f modalField) encode(rv reflect.Value) string {
+	fv := rv.FieldByIndex(f.index)
+	if f.pointer {
+		if fv.IsNil() {
+			return ""
+		}
+		fv = fv.Elem()
+	}
+	if fv.IsZero() && !f.isString() {
+		return ""
+	}
+	return fmt.Sprint(fv.Interface())
+}
+
+// モーダルの入力を構造体にデコードする
+func DecodeModal[T any](data discord.ModalSubmitInteractionData) (T, error) {
+	return decodeModal[T](data.OptText)
+}
+
+func decodeModal[T any](text func(customID string) (string, bool)) (T, error) {
+	var fields T
+	rv := reflect.ValueOf(&fields).Elem()
+	modalFields, err := modalFieldsOf(rv.Type())
+	if err != nil {
+		return fields, err
+	}
+	for _, f := range modalFields {
+		s, ok := text(f.name)
+		if !ok || s == "" {
+			if f.required {
+				return fields, &OptionError{
+					Option: f.name,
+					Key:    OptionErrorRequired,
+					Data:   map[string]any{"Option": f.label},
+				}
+			}
+			continue
+		}
+		value, err := f.decode(s)
+		if err != nil {
+			return fields, err
+		}
+		fv := rv.FieldByIndex(f.index)
+		if f.pointer {
+			p := reflect.New(f.typ)
+			p.Elem().Set(value)
+			fv.Set(p)
+		} else {
+			fv.Set(value)
+		}
+	}
+	if validator, ok := any(&fields).(ModalValidator); ok {
+		if err := validator.ValidateModal(); err != nil {
+			return fields, asOptionError("", err)
+		}
+	}
+	return fields, nil
+}
+
+// 検証のエラーをOptionErrorにする
+func asOptionError(option string, err error) error {
+	var optionErr *OptionError
+	if errors.As(err, &optionErr) {
+		return err
+	}
+	return &OptionError{
+		Option: option,
+		Key:    OptionErrorInvalid,
+		Data:   map[string]any{"Option": option, "Error": err.Error()},
+		Err:    err,
+	}
+}
+
+// 入力欄を構造体で宣言するモーダル
+//
+//	type ReportFields struct {
+//		Title  string `field:"title,required,max=100" label:"タイトル" localize:"modal_report_title"`
+//		Body   string `field:"body,paragraph,max=2000" label:"内容" placeholder:"詳しく書いてください"`
+//		Amount *int   `field:"amount,min=1,max=10" label:"数"`
+//	}
+//
+// fieldタグには名前に続けて required, paragraph, min=, max= を指定できる
+// 文字列のmin, maxは文字数、数値のmin, maxは値の範囲になる
+// localizeタグを指定した場合は キー_label と キー_placeholder の翻訳を使う
+type ModalForm[T any] struct {
+	// カスタムIDの名前
+	Name     string
+	Title    string
+	TitleKey string
+
+	Check       Check[*events.ModalSubmitInteractionCreate]
+	Ephemeral   bool
+	Middlewares []Middleware
+	// 入力欄の名前ごとの検証
+	Validators map[string]func(value string) error
+	// trueの場合は検証に失敗した時に入力をやり直すボタンを付けて応答する
+	// falseの場合はエラーメッセージだけを応答する
+	Reprompt bool
+	Handler  TypedModalHandler[T]
+	OnError  ErrorHandler
+
+	handler *Handler
+}
+
+// モーダルと再入力のボタンをハンダラに登録する
+func (f *ModalForm[T]) Register(h *Handler) error {
+	if _, err := modalFieldsOf(reflect.TypeOf((*T)(nil)).Elem()); err != nil {
+		return err
+	}
+	f.handler = h
+	h.AddModal(Modal{
+		Name:        f.Name,
+		Checks:      map[string]Check[*events.ModalSubmitInteractionCreate]{modalFormSubmit: f.Check},
+		Ephemeral:   map[string]bool{modalFormSubmit: f.Ephemeral},
+		Middlewares: f.Middlewares,
+		OnError:     f.OnError,
+		ContextHandler: map[string]ModalContextHandler{
+			modalFormSubmit: f.handle,
+		},
+	})
+	h.AddComponent(Component{
+		Name: f.Name,
+		ContextHandler: map[string]ComponentContextHandler{
+			modalFormRetry: f.retry,
+		},
+	})
+	return nil
+}
+
+// モーダルのカスタムIDのビルダーを返す
+// 引数や状態を加えてCreateに渡す
+func (f *ModalForm[T]) CustomID() *customid.Builder {
+	return customid.New(f.Name, modalFormSubmit)
+}
+
+// モーダルを作成する
+// idがnilの場合はCustomID()を使い、initialがnilでない場合は入力欄に値を表示する
+func (f *ModalForm[T]) Create(locale discord.Locale, id *customid.Builder, initial *T) (discord.ModalCreate, error) {
+	if id == nil {
+		id = f.CustomID()
+	}
+	customID, err := f.handler.EncodeCustomID(id)
+	if err != nil {
+		return discord.ModalCreate{}, err
+	}
+	values := map[string]string{}
+	if initial != nil {
+		fields, err := modalFieldsOf(reflect.TypeOf(initial).Elem())
+		if err != nil {
+			return discord.ModalCreate{}, err
+		}
+		rv := reflect.ValueOf(initial).Elem()
+		for _, field := range fields {
+			values[field.name] = field.encode(rv)
+		}
+	}
+	return f.modal(locale, customID, values)
+}
+
+func (f *ModalForm[T]) modal(locale discord.Locale, customID string, values map[string]string) (discord.ModalCreate, error) {
+	fields, err := modalFieldsOf(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return discord.ModalCreate{}, err
+	}
+	title := f.Title
+	if f.TitleKey != "" {
+		title = translate.Message(locale, f.TitleKey, translate.WithFallback(title))
+	}
+	components := make([]discord.ContainerComponent, 0, len(fields))
+	for _, field := range fields {
+		components = append(components, discord.NewActionRow(field.component(locale, values[field.name])))
+	}
+	return discord.ModalCreate{
+		CustomID:   customID,
+		Title:      title,
+		Components: components,
+	}, nil
+}
+
+type modalFormRetryState struct {
+	customID string
+	values   map[string]string
+}
+
+func (f *ModalForm[T]) handle(ctx context.Context, event *events.ModalSubmitInteractionCreate) error {
+	err := f.validate(event.Data)
+	var fields T
+	if err == nil {
+		fields, err = DecodeModal[T](event.Data)
+	}
+	if err != nil {
+		if !f.Reprompt {
+			return err
+		}
+		return f.reprompt(ctx, event, err)
+	}
+	if f.Handler == nil {
+		return nil
+	}
+	return f.Handler(ctx, event, fields)
+}
+
+func (f *ModalForm[T]) validate(data discord.ModalSubmitInteractionData) error {
+	for name, validate := range f.Validators {
+		text, _ := data.OptText(name)
+		if err := validate(text); err != nil {
+			return asOptionError(name, err)
+		}
+	}
+	return nil
+}
+
+// エラーメッセージと入力をやり直すボタンを応答する
+func (f *ModalForm[T]) reprompt(ctx context.Context, event *events.ModalSubmitInteractionCreate, err error) error {
+	fields, ferr := modalFieldsOf(reflect.TypeOf((*T)(nil)).Elem())
+	if ferr != nil {
+		return ferr
+	}
+	state := modalFormRetryState{customID: event.Data.CustomID, values: map[string]string{}}
+	for _, field := range fields {
+		state.values[field.name], _ = event.Data.OptText(field.name)
+	}
+	customID, ferr := f.handler.EncodeCustomIDWithState(ctx, customid.New(f.Name, modalFormRetry), state)
+	if ferr != nil {
+		return ferr
+	}
+	locale := event.Locale()
+	label := translate.Message(locale, "modal_retry", translate.WithFallback("入力し直す"))
+	return event.CreateMessage(discord.MessageCreate{
+		Embeds: f.handler.errorEmbeds(locale, AsError(err)),
+		Components: []discord.ContainerComponent{
+			discord.NewActionRow(discord.NewPrimaryButton(label, customID)),
+		},
+		Flags: discord.MessageFlagEphemeral,
+	})
+}
+
+// 前回の入力を表示したモーダルを開き直す
+func (f *ModalForm[T]) retry(ctx context.Context, event *events.ComponentInteractionCreate) error {
+	state, ok := State[modalFormRetryState](ctx)
+	if !ok {
+		return f.handler.stateExpired(ctx, event)
+	}
+	modal, err := f.modal(event.Locale(), state.customID, state.values)
+	if err != nil {
+		return err
+	}
+	return event.CreateModal(modal)
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/disgoorg/log"
+	"github.com/sabafly/sabafly-disgo/discord"
+)
+
+type testModalFields struct {
+	Title   string `field:"title,required,min=2,max=10" label:"Title"`
+	Body    string `field:"body,paragraph" label:"Body" placeholder:"body"`
+	Amount  *int   `field:"amount,min=1,max=5" label:"Amount"`
+	Ignored string
+}
+
+func (f *testModalFields) ValidateModal() error {
+	if f.Title == "forbidden" {
+		return errors.New("forbidden title")
+	}
+	return nil
+}
+
+func TestDecodeModal(t *testing.T) {
+	tests := map[string]struct {
+		values map[string]string
+		key    string
+	}{
+		"valid":          {map[string]string{"title": "hello", "amount": "3"}, ""},
+		"optional empty": {map[string]string{"title": "hello", "amount": ""}, ""},
+		"required":       {map[string]string{"body": "text"}, OptionErrorRequired},
+		"too short":      {map[string]string{"title": "a"}, OptionErrorOutOfRange},
+		"out of range":   {map[string]string{"title": "hello", "amount": "6"}, OptionErrorOutOfRange},
+		"not a number":   {map[string]string{"title": "hello", "amount": "three"}, OptionErrorInvalid},
+		"validator":      {map[string]string{"title": "forbidden"}, OptionErrorInvalid},
+	}
+	for name, tt := range tests {
+		fields, err := decodeModal[testModalFields](func(customID string) (string, bool) {
+			v, ok := tt.values[customID]
+			return v, ok
+		})
+		if tt.key == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", name, err)
+			}
+			continue
+		}
+		var optionErr *OptionError
+		if !errors.As(err, &optionErr) || optionErr.Key != tt.key {
+			t.Errorf("%s: expected %s, got %v (%+v)", name, tt.key, err, fields)
+		}
+	}
+
+	fields, err := decodeModal[testModalFields](func(customID string) (string, bool) {
+		return map[string]string{"title": "hello", "amount": "3"}[customID], true
+	})
+	if err != nil || fields.Title != "hello" || fields.Amount == nil || *fields.Amount != 3 {
+		t.Errorf("unexpected fields %+v %v", fields, err)
+	}
+}
+
+func TestDecodeModalOverflow(t *testing.T) {
+	type fields struct {
+		Count int8    `field:"count" label:"Count"`
+		Ratio float32 `field:"ratio" label:"Ratio"`
+	}
+	tests := map[string]struct {
+		values map[string]string
+		key    string
+	}{
+		"in range":       {map[string]string{"count": "-128", "ratio": "0.5"}, ""},
+		"int overflow":   {map[string]string{"count": "300"}, OptionErrorOutOfRange},
+		"float overflow": {map[string]string{"ratio": "1e40"}, OptionErrorOutOfRange},
+		"not a number":   {map[string]string{"count": "many"}, OptionErrorInvalid},
+	}
+	for name, tt := range tests {
+		_, err := decodeModal[fields](func(customID string) (string, bool) {
+			v, ok := tt.values[customID]
+			return v, ok
+		})
+		if tt.key == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", name, err)
+			}
+			continue
+		}
+		var optionErr *OptionError
+		if !errors.As(err, &optionErr) || optionErr.Key != tt.key {
+			t.Errorf("%s: expected %s, got %v", name, tt.key, err)
+		}
+	}
+}
+
+func TestModalFormCreate(t *testing.T) {
+	h := New(log.Default())
+	form := &ModalForm[testModalFields]{Name: "report", Title: "Report"}
+	if err := form.Register(h); err != nil {
+		t.Fatal(err)
+	}
+	amount := 2
+	modal, err := form.Create(discord.LocaleJapanese, nil, &testModalFields{Title: "hi", Amount: &amount})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if modal.Title != "Report" || len(modal.Components) != 3 {
+		t.Fatalf("unexpected modal %+v", modal)
+	}
+	var inputs []discord.TextInputComponent
+	for _, row := range modal.Components {
+		inputs = append(inputs, row.Components()[0].(discord.TextInputComponent))
+	}
+	if title := inputs[0]; !title.Required || title.Value != "hi" || title.MinLength == nil || *title.MinLength != 2 || title.MaxLength != 10 {
+		t.Errorf("unexpected title input %+v", title)
+	}
+	if body := inputs[1]; body.Style != discord.TextInputStyleParagraph || body.Value != "" {
+		t.Errorf("unexpected body input %+v", body)
+	}
+	if amount := inputs[2]; amount.Value != "2" || amount.MinLength != nil || amount.MaxLength != 0 {
+		t.Errorf("unexpected amount input %+v", amount)
+	}
+
+	type tooMany struct {
+		A, B, C, D, E, F string `field:""`
+	}
+	if err := (&ModalForm[tooMany]{Name: "many"}).Register(h); err == nil {
+		t.Error("expected error for too many fields")
+	}
+}
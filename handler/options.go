@@ -0,0 +1,534 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/disgoorg/json"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+	"github.com/sabafly/sabafly-lib/v2/translate"
+)
+
+type (
+	TypedCommandHandler[T any] func(ctx context.Context, event *events.ApplicationCommandInteractionCreate, options T) error
+
+	// デコード後に呼び出される検証
+	OptionValidator interface {
+		ValidateOptions() error
+	}
+)
+
+const (
+	OptionErrorRequired   = "option_required"
+	OptionErrorOutOfRange = "option_out_of_range"
+	OptionErrorInvalid    = "option_invalid"
+)
+
+// オプションの検証に失敗したことを表す
+// Keyは応答に使う翻訳キー
+type OptionError struct {
+	Option string
+	Key    string
+	Data   map[string]any
+	Err    error
+}
+
+func (e *OptionError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("invalid option %q: %s: %s", e.Option, e.Key, e.Err)
+	}
+	return fmt.Sprintf("invalid option %q: %s", e.Option, e.Key)
+}
+
+func (e *OptionError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	userType       = reflect.TypeOf(discord.User{})
+	memberType     = reflect.TypeOf(discord.ResolvedMember{})
+	roleType       = reflect.TypeOf(discord.Role{})
+	channelType    = reflect.TypeOf(discord.ResolvedChannel{})
+	attachmentType = reflect.TypeOf(discord.Attachment{})
+	snowflakeType  = reflect.TypeOf(snowflake.ID(0))
+
+	optionFieldsCache sync.Map
+)
+
+type optionChoice struct {
+	name  string
+	value string
+}
+
+type optionField struct {
+	index        []int
+	typ          reflect.Type
+	pointer      bool
+	optionType   discord.ApplicationCommandOptionType
+	name         string
+	description  string
+	localize     string
+	required     bool
+	autocomplete bool
+	min          *float64
+	max          *float64
+	choices      []optionChoice
+	channelTypes []discord.ChannelType
+}
+
+func optionFieldsOf(t reflect.Type) ([]optionField, error) {
+	if v, ok := optionFieldsCache.Load(t); ok {
+		return v.([]optionField), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("options must be a struct, got %s", t)
+	}
+	var fields []optionField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("option")
+		if !ok || tag == "-" || !sf.IsExported() {
+			continue
+		}
+		field, err := parseOptionField(sf, tag)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+		fields = append(fields, field)
+	}
+	optionFieldsCache.Store(t, fields)
+	return fields, nil
+}
+
+func parseOptionField(sf reflect.StructField, tag string) (optionField, error) {
+	field := optionField{
+		index:       sf.Index,
+		typ:         sf.Type,
+		description: sf.Tag.Get("description"),
+		localize:    sf.Tag.Get("localize"),
+	}
+	if field.typ.Kind() == reflect.Pointer {
+		field.pointer = true
+		field.typ = field.typ.Elem()
+	}
+	optionType, err := optionTypeOf(field.typ)
+	if err != nil {
+		return field, err
+	}
+	field.optionType = optionType
+
+	parts := strings.Split(tag, ",")
+	field.name = parts[0]
+	if field.name == "" {
+		field.name = strings.ToLower(sf.Name)
+	}
+	for _, part := range parts[1:] {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "required":
+			field.required = true
+		case "autocomplete":
+			field.autocomplete = true
+		case "min", "max":
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return field, fmt.Errorf("invalid %s %q: %w", key, value, err)
+			}
+			if key == "min" {
+				field.min = &f
+			} else {
+				field.max = &f
+			}
+		case "choices":
+			for _, c := range strings.Split(value, "|") {
+				name, v, ok := strings.Cut(c, ":")
+				if !ok {
+					v = name
+				}
+				field.choices = append(field.choices, optionChoice{name: name, value: v})
+			}
+		case "channel_types":
+			for _, c := range strings.Split(value, "|") {
+				n, err := strconv.Atoi(c)
+				if err != nil {
+					return field, fmt.Errorf("invalid channel type %q: %w", c, err)
+				}
+				field.channelTypes = append(field.channelTypes, discord.ChannelType(n))
+			}
+		default:
+			return field, fmt.Errorf("unknown option tag %q", key)
+		}
+	}
+	return field, nil
+}
+
+func optionTypeOf(t reflect.Type) (discord.ApplicationCommandOptionType, error) {
+	switch t {
+	case userType, memberType:
+		return discord.ApplicationCommandOptionTypeUser, nil
+	case roleType:
+		return discord.ApplicationCommandOptionTypeRole, nil
+	case channelType:
+		return discord.ApplicationCommandOptionTypeChannel, nil
+	case attachmentType:
+		return discord.ApplicationCommandOptionTypeAttachment, nil
+	case snowflakeType:
+		return discord.ApplicationCommandOptionTypeMentionable, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return discord.ApplicationCommandOptionTypeString, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return discord.ApplicationCommandOptionTypeInt, nil
+	case reflect.Float32, reflect.Float64:
+		return discord.ApplicationCommandOptionTypeFloat, nil
+	case reflect.Bool:
+		return discord.ApplicationCommandOptionTypeBool, nil
+	}
+	return 0, fmt.Errorf("unsupported option type %s", t)
+}
+
+func intPtr(f *float64) *int {
+	if f == nil {
+		return nil
+	}
+	return json.Ptr(int(*f))
+}
+
+func (f optionField) option() (discord.ApplicationCommandOption, error) {
+	var nameLocalizations, descriptionLocalizations map[discord.Locale]string
+	if f.localize != "" {
+		nameLocalizations = translate.MessageMap(f.localize+"_name", true)
+		descriptionLocalizations = translate.MessageMap(f.localize+"_description", false)
+	}
+	switch f.optionType {
+	case discord.ApplicationCommandOptionTypeString:
+		option := discord.ApplicationCommandOptionString{
+			Name:                     f.name,
+			NameLocalizations:        nameLocalizations,
+			Description:              f.description,
+			DescriptionLocalizations: descriptionLocalizations,
+			Required:                 f.required,
+			Autocomplete:             f.autocomplete,
+			MinLength:                intPtr(f.min),
+			MaxLength:                intPtr(f.max),
+		}
+		for _, c := range f.choices {
+			option.Choices = append(option.Choices, discord.ApplicationCommandOptionChoiceString{Name: c.name, Value: c.value})
+		}
+		return option, nil
+	case discord.ApplicationCommandOptionTypeInt:
+		option := discord.ApplicationCommandOptionInt{
+			Name:                     f.name,
+			NameLocalizations:        nameLocalizations,
+			Description:              f.description,
+			DescriptionLocalizations: descriptionLocalizations,
+			Required:                 f.required,
+			Autocomplete:             f.autocomplete,
+			MinValue:                 intPtr(f.min),
+			MaxValue:                 intPtr(f.max),
+		}
+		for _, c := range f.choices {
+			v, err := strconv.Atoi(c.value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid choice %q for %s: %w", c.value, f.name, err)
+			}
+			option.Choices = append(option.Choices, discord.ApplicationCommandOptionChoiceInt{Name: c.name, Value: v})
+		}
+		return option, nil
+	case discord.ApplicationCommandOptionTypeFloat:
+		option := discord.ApplicationCommandOptionFloat{
+			Name:                     f.name,
+			NameLocalizations:        nameLocalizations,
+			Description:              f.description,
+			DescriptionLocalizations: descriptionLocalizations,
+			Required:                 f.required,
+			Autocomplete:             f.autocomplete,
+			MinValue:                 f.min,
+			MaxValue:                 f.max,
+		}
+		for _, c := range f.choices {
+			v, err := strconv.ParseFloat(c.value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid choice %q for %s: %w", c.value, f.name, err)
+			}
+			option.Choices = append(option.Choices, discord.ApplicationCommandOptionChoiceFloat{Name: c.name, Value: v})
+		}
+		return option, nil
+	case discord.ApplicationCommandOptionTypeBool:
+		return discord.ApplicationCommandOptionBool{
+			Name:                     f.name,
+			NameLocalizations:        nameLocalizations,
+			Description:              f.description,
+			DescriptionLocalizations: descriptionLocalizations,
+			Required:                 f.required,
+		}, nil
+	case discord.ApplicationCommandOptionTypeUser:
+		return discord.ApplicationCommandOptionUser{
+			Name:                     f.name,
+			NameLocalizations:        nameLocalizations,
+			Description:              f.description,
+			DescriptionLocalizations: descriptionLocalizations,
+			Required:                 f.required,
+		}, nil
+	case discord.ApplicationCommandOptionTypeRole:
+		return discord.ApplicationCommandOptionRole{
+			Name:                     f.name,
+			NameLocalizations:        nameLocalizations,
+			Description:              f.description,
+			DescriptionLocalizations: descriptionLocalizations,
+			Required:                 f.required,
+		}, nil
+	case discord.ApplicationCommandOptionTypeChannel:
+		return discord.ApplicationCommandOptionChannel{
+			Name:                     f.name,
+			NameLocalizations:        nameLocalizations,
+			Description:              f.description,
+			DescriptionLocalizations: descriptionLocalizations,
+			Required:                 f.required,
+			ChannelTypes:             f.channelTypes,
+		}, nil
+	case discord.ApplicationCommandOptionTypeMentionable:
+		return discord.ApplicationCommandOptionMentionable{
+			Name:                     f.name,
+			NameLocalizations:        nameLocalizations,
+			Description:              f.description,
+			DescriptionLocalizations: descriptionLocalizations,
+			Required:                 f.required,
+		}, nil
+	case discord.ApplicationCommandOptionTypeAttachment:
+		return discord.ApplicationCommandOptionAttachment{
+			Name:                     f.name,
+			NameLocalizations:        nameLocalizations,
+			Description:              f.description,
+			DescriptionLocalizations: descriptionLocalizations,
+			Required:                 f.required,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported option type %d", f.optionType)
+}
+
+// 構造体のタグからコマンドのオプションを生成する
+// 必須のオプションは任意のオプションより前に並べる
+//
+//	type BanOptions struct {
+//		User   discord.User `option:"user,required" description:"対象のユーザー" localize:"command_ban_user"`
+//		Days   *int         `option:"days,min=0,max=7" description:"削除するメッセージの日数"`
+//		Reason string       `option:"reason,max=512" description:"理由"`
+//	}
+//
+// optionタグには名前に続けて required, autocomplete, min=, max=,
+// choices=名前:値|名前:値, channel_types=0|5 を指定できる
+// localizeタグを指定した場合は キー_name と キー_description の翻訳を使う
+func OptionsOf[T any]() ([]discord.ApplicationCommandOption, error) {
+	fields, err := optionFieldsOf(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	var required, optional []discord.ApplicationCommandOption
+	for _, f := range fields {
+		option, err := f.option()
+		if err != nil {
+			return nil, err
+		}
+		if f.required {
+			required = append(required, option)
+		} else {
+			optional = append(optional, option)
+		}
+	}
+	return append(required, optional...), nil
+}
+
+func (f optionField) decode(data discord.SlashCommandInteractionData) (reflect.Value, bool, error) {
+	var (
+		v  any
+		ok bool
+	)
+	switch f.typ {
+	case userType:
+		v, ok = data.OptUser(f.name)
+	case memberType:
+		v, ok = data.OptMember(f.name)
+	case roleType:
+		v, ok = data.OptRole(f.name)
+	case channelType:
+		v, ok = data.OptChannel(f.name)
+	case attachmentType:
+		v, ok = data.OptAttachment(f.name)
+	case snowflakeType:
+		v, ok = data.OptSnowflake(f.name)
+	default:
+		switch f.optionType {
+		case discord.ApplicationCommandOptionTypeString:
+			v, ok = data.OptString(f.name)
+		case discord.ApplicationCommandOptionTypeInt:
+			v, ok = data.OptInt(f.name)
+		case discord.ApplicationCommandOptionTypeFloat:
+			v, ok = data.OptFloat(f.name)
+		case discord.ApplicationCommandOptionTypeBool:
+			v, ok = data.OptBool(f.name)
+		}
+	}
+	if !ok {
+		return reflect.Value{}, false, nil
+	}
+	value := reflect.ValueOf(v)
+	// フィールドの型に収まらない値は切り詰めずにエラーにする
+	var overflow bool
+	switch f.typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		overflow = reflect.Zero(f.typ).OverflowInt(value.Int())
+	case reflect.Float32, reflect.Float64:
+		overflow = reflect.Zero(f.typ).OverflowFloat(value.Float())
+	}
+	if overflow {
+		return reflect.Value{}, true, &OptionError{
+			Option: f.name,
+			Key:    OptionErrorOutOfRange,
+			Data:   map[string]any{"Option": f.name, "Min": f.min, "Max": f.max},
+		}
+	}
+	return value.Convert(f.typ), true, nil
+}
+
+func (f optionField) validate(value reflect.Value) error {
+	var n float64
+	switch f.optionType {
+	case discord.ApplicationCommandOptionTypeString:
+		n = float64(utf8.RuneCountInString(value.String()))
+	case discord.ApplicationCommandOptionTypeInt:
+		n = float64(value.Int())
+	case discord.ApplicationCommandOptionTypeFloat:
+		n = value.Float()
+	default:
+		return nil
+	}
+	if (f.min != nil && n < *f.min) || (f.max != nil && n > *f.max) {
+		return &OptionError{
+			Option: f.name,
+			Key:    OptionErrorOutOfRange,
+			Data:   map[string]any{"Option": f.name, "Min": f.min, "Max": f.max},
+		}
+	}
+	return nil
+}
+
+// インタラクションのオプションを構造体にデコードする
+func DecodeOptions[T any](data discord.SlashCommandInteractionData) (T, error) {
+	var options T
+	rv := reflect.ValueOf(&options).Elem()
+	fields, err := optionFieldsOf(rv.Type())
+	if err != nil {
+		return options, err
+	}
+	for _, f := range fields {
+		value, ok, err := f.decode(data)
+		if err != nil {
+			return options, err
+		}
+		if !ok {
+			if f.required {
+				return options, &OptionError{
+					Option: f.name,
+					Key:    OptionErrorRequired,
+					Data:   map[string]any{"Option": f.name},
+				}
+			}
+			continue
+		}
+		if err := f.validate(value); err != nil {
+			return options, err
+		}
+		fv := rv.FieldByIndex(f.index)
+		if f.pointer {
+			p := reflect.New(f.typ)
+			p.Elem().Set(value)
+			fv.Set(p)
+		} else {
+			fv.Set(value)
+		}
+	}
+	if validator, ok := any(&options).(OptionValidator); ok {
+		if err := validator.ValidateOptions(); err != nil {
+			var optionErr *OptionError
+			if errors.As(err, &optionErr) {
+				return options, err
+			}
+			return options, &OptionError{
+				Key:  OptionErrorInvalid,
+				Data: map[string]any{"Error": err.Error()},
+				Err:  err,
+			}
+		}
+	}
+	return options, nil
+}
+
+// 型付きのハンダラをコマンドハンダラに変換する
+func TypedHandler[T any](handler TypedCommandHandler[T]) CommandContextHandler {
+	return func(ctx context.Context, event *events.ApplicationCommandInteractionCreate) error {
+		data, ok := event.Data.(discord.SlashCommandInteractionData)
+		if !ok {
+			return fmt.Errorf("unexpected command data %T", event.Data)
+		}
+		options, err := DecodeOptions[T](data)
+		if err != nil {
+			return err
+		}
+		return handler(ctx, event, options)
+	}
+}
+
+// オプションを構造体で宣言するスラッシュコマンド
+type SlashCommand[T any] struct {
+	Name                     string
+	NameLocalizations        map[discord.Locale]string
+	Description              string
+	DescriptionLocalizations map[discord.Locale]string
+	DefaultMemberPermissions *json.Nullable[discord.Permissions]
+	DMPermission             *bool
+	NSFW                     bool
+
+	Check       Check[*events.ApplicationCommandInteractionCreate]
+	Ephemeral   bool
+	Middlewares []Middleware
+	Handler     TypedCommandHandler[T]
+
+	DevOnly bool
+}
+
+// コマンドの作成データとハンダラを生成する
+func (c SlashCommand[T]) Command() (Command, error) {
+	options, err := OptionsOf[T]()
+	if err != nil {
+		return Command{}, err
+	}
+	return Command{
+		Create: discord.SlashCommandCreate{
+			Name:                     c.Name,
+			NameLocalizations:        c.NameLocalizations,
+			Description:              c.Description,
+			DescriptionLocalizations: c.DescriptionLocalizations,
+			Options:                  options,
+			DefaultMemberPermissions: c.DefaultMemberPermissions,
+			DMPermission:             c.DMPermission,
+			NSFW:                     c.NSFW,
+		},
+		Check:       c.Check,
+		Ephemeral:   map[string]bool{"": c.Ephemeral},
+		Middlewares: c.Middlewares,
+		CommandContextHandlers: map[string]CommandContextHandler{
+			"": TypedHandler(c.Handler),
+		},
+		DevOnly: c.DevOnly,
+	}, nil
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/disgoorg/json"
+	"github.com/disgoorg/log"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+	"github.com/sabafly/sabafly-disgo/rest"
+)
+
+type testOptions struct {
+	Reason  string                  `option:"reason,max=512" description:"reason"`
+	User    discord.User            `option:"user,required" description:"user"`
+	Days    *int                    `option:"days,min=0,max=7,choices=zero:0|seven:7" description:"days"`
+	Channel discord.ResolvedChannel `option:"channel,channel_types=0|5" description:"channel"`
+	Ignored string
+}
+
+func TestOptionsOf(t *testing.T) {
+	options, err := OptionsOf[testOptions]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(options) != 4 {
+		t.Fatalf("expected 4 options, got %d", len(options))
+	}
+	if _, ok := options[0].(discord.ApplicationCommandOptionUser); !ok {
+		t.Errorf("expected required user option first, got %T", options[0])
+	}
+	days, ok := options[2].(discord.ApplicationCommandOptionInt)
+	if !ok {
+		t.Fatalf("expected int option, got %T", options[2])
+	}
+	if days.Required || days.MinValue == nil || *days.MinValue != 0 || days.MaxValue == nil || *days.MaxValue != 7 {
+		t.Errorf("unexpected days option %+v", days)
+	}
+	if len(days.Choices) != 2 || days.Choices[1].Value != 7 {
+		t.Errorf("unexpected days choices %+v", days.Choices)
+	}
+	channel, ok := options[3].(discord.ApplicationCommandOptionChannel)
+	if !ok || len(channel.ChannelTypes) != 2 {
+		t.Errorf("unexpected channel option %+v", options[3])
+	}
+}
+
+func TestOptionsOfInvalid(t *testing.T) {
+	type unsupported struct {
+		Value []string `option:"value"`
+	}
+	if _, err := OptionsOf[unsupported](); err == nil {
+		t.Error("expected error for unsupported field type")
+	}
+	type unknownTag struct {
+		Value string `option:"value,unknown"`
+	}
+	if _, err := OptionsOf[unknownTag](); err == nil {
+		t.Error("expected error for unknown tag")
+	}
+}
+
+type testDecodeOptions struct {
+	Name  string   `option:"name,required,min=2,max=5" description:"name"`
+	Days  *int     `option:"days,min=0,max=7" description:"days"`
+	Small int8     `option:"small" description:"small"`
+	Ratio float32  `option:"ratio" description:"ratio"`
+	Score *float64 `option:"score" description:"score"`
+}
+
+func testOptionData(values map[string]string) discord.SlashCommandInteractionData {
+	options := map[string]discord.SlashCommandOption{}
+	for name, value := range values {
+		options[name] = discord.SlashCommandOption{Name: name, Value: json.RawMessage(value)}
+	}
+	return discord.SlashCommandInteractionData{Options: options}
+}
+
+func TestDecodeOptions(t *testing.T) {
+	options, err := DecodeOptions[testDecodeOptions](testOptionData(map[string]string{
+		"name":  `"abc"`,
+		"days":  "7",
+		"small": "-128",
+		"ratio": "0.5",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.Name != "abc" || options.Days == nil || *options.Days != 7 || options.Small != -128 || options.Ratio != 0.5 || options.Score != nil {
+		t.Errorf("unexpected options %+v", options)
+	}
+
+	tests := map[string]struct {
+		values map[string]string
+		key    string
+	}{
+		"missing required": {map[string]string{"days": "1"}, OptionErrorRequired},
+		"too short":        {map[string]string{"name": `"a"`}, OptionErrorOutOfRange},
+		"too long":         {map[string]string{"name": `"abcdef"`}, OptionErrorOutOfRange},
+		"below min":        {map[string]string{"name": `"abc"`, "days": "-1"}, OptionErrorOutOfRange},
+		"above max":        {map[string]string{"name": `"abc"`, "days": "8"}, OptionErrorOutOfRange},
+		"int overflow":     {map[string]string{"name": `"abc"`, "small": "300"}, OptionErrorOutOfRange},
+		"float overflow":   {map[string]string{"name": `"abc"`, "ratio": "1e40"}, OptionErrorOutOfRange},
+	}
+	for name, tt := range tests {
+		_, err := DecodeOptions[testDecodeOptions](testOptionData(tt.values))
+		var optionErr *OptionError
+		if !errors.As(err, &optionErr) || optionErr.Key != tt.key {
+			t.Errorf("%s: expected %s, got %v", name, tt.key, err)
+		}
+	}
+}
+
+func TestTypedHandlerValidationReply(t *testing.T) {
+	h := New(log.Default())
+	h.ErrorEmbeds = func(_ discord.Locale, err *Error) []discord.Embed {
+		return []discord.Embed{{Title: err.Key}}
+	}
+	var replies []discord.MessageCreate
+	tracker := h.newAckTracker(nil, testInteraction{}, func(_ discord.InteractionResponseType, data discord.InteractionResponseData, _ ...rest.RequestOpt) error {
+		replies = append(replies, data.(discord.MessageCreate))
+		return nil
+	})
+	var called bool
+	handler := TypedHandler(func(context.Context, *events.ApplicationCommandInteractionCreate, testDecodeOptions) error {
+		called = true
+		return nil
+	})
+	event := &events.ApplicationCommandInteractionCreate{
+		ApplicationCommandInteraction: discord.ApplicationCommandInteraction{
+			Data: testOptionData(map[string]string{"name": `"abc"`, "small": "300"}),
+		},
+	}
+	err := handler(context.Background(), event)
+	if called {
+		t.Error("expected handler not to be called with invalid options")
+	}
+	// 検証エラーは利用者のエラーとして応答される
+	h.handleError(context.Background(), event, discord.LocaleJapanese, tracker, nil, "test", err)
+	if len(replies) != 1 || replies[0].Embeds[0].Title != OptionErrorOutOfRange || replies[0].Flags != discord.MessageFlagEphemeral {
+		t.Fatalf("unexpected replies %+v", replies)
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/sabafly/sabafly-disgo/bot"
+)
+
+// ハンダラで発生したパニックの報告
+// エラーとしてOnErrorやエラーメッセージの応答に渡される
+type PanicReport struct {
+	// パニックが発生したハンダラ
+	Source string
+	Event  bot.Event
+	Value  any
+	Stack  []byte
+	Time   time.Time
+}
+
+func (r *PanicReport) Error() string {
+	return fmt.Sprintf("panic in %s: %v", r.Source, r.Value)
+}
+
+// パニックの報告を受け取る
+type PanicReporter func(report *PanicReport)
+
+// ハンダラをひとつ実行する
+// パニックが発生した場合は報告し、PanicReportをエラーとして返す
+func (h *Handler) invoke(source string, event bot.Event, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			report := &PanicReport{
+				Source: source,
+				Event:  event,
+				Value:  r,
+				Stack:  debug.Stack(),
+				Time:   time.Now(),
+			}
+			h.reportPanic(report)
+			err = report
+		}
+	}()
+	return fn()
+}
+
+func (h *Handler) reportPanic(report *PanicReport) {
+	h.Logger.Errorf("%s\n%s", report, report.Stack)
+	h.mu.RLock()
+	reporters := append([]PanicReporter{}, h.PanicReporters...)
+	h.mu.RUnlock()
+	for _, reporter := range reporters {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					h.Logger.Errorf("panic in panic reporter: %v", r)
+				}
+			}()
+			reporter(report)
+		}()
+	}
+}
+
+// パニックの報告先を追加する
+func (h *Handler) AddPanicReporter(reporter PanicReporter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.PanicReporters = append(h.PanicReporters, reporter)
+}
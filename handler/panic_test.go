@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/disgoorg/log"
+)
+
+func TestPanicIsolation(t *testing.T) {
+	h := New(log.Default())
+	var reports []*PanicReport
+	h.AddPanicReporter(func(report *PanicReport) {
+		reports = append(reports, report)
+	})
+	type testEvent struct{}
+	list := newGenericsList[testEvent](h)
+	var called bool
+	list.Adds(
+		Generics[testEvent]{Handler: func(*testEvent) error { panic("boom") }},
+		Generics[testEvent]{Handler: func(*testEvent) error {
+			called = true
+			return nil
+		}},
+	)
+	list.handleEvent(context.Background(), &testEvent{})
+	if !called {
+		t.Error("expected second handler to be called after a panic")
+	}
+	if len(reports) != 1 || reports[0].Value != "boom" || len(reports[0].Stack) == 0 {
+		t.Fatalf("unexpected reports %+v", reports)
+	}
+
+	err := h.invoke("test", nil, func() error { panic("again") })
+	if _, ok := err.(*PanicReport); !ok {
+		t.Errorf("expected panic report error, got %v", err)
+	}
+	if AsError(err).Kind != ErrorKindInternal {
+		t.Error("expected panic to be an internal error")
+	}
+}
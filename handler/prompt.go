@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+	"github.com/sabafly/sabafly-disgo/rest"
+	"github.com/sabafly/sabafly-lib/v2/translate"
+)
+
+// 確認や選択を待つ既定の時間
+const DefaultPromptTimeout = time.Minute
+
+// 呼び出したユーザー以外がプロンプトを操作した時のエラーメッセージの翻訳キー
+const PromptNotOwnerMessage = "prompt_not_owner"
+
+var (
+	// 期限までに選択されなかった
+	ErrPromptTimeout = UserError("prompt_timeout")
+	// コンテキストにインタラクションがない
+	ErrNoInteraction = errors.New("no interaction in context")
+)
+
+// プロンプトを表示するインタラクション
+type PromptInteraction interface {
+	User() discord.User
+	Locale() discord.Locale
+}
+
+type Prompt struct {
+	// 表示するメッセージ
+	// Componentsは上書きされる
+	Message   discord.MessageCreate
+	Ephemeral bool
+	// 0の場合はDefaultPromptTimeout
+	Timeout time.Duration
+}
+
+// 確認ダイアログ
+type ConfirmPrompt struct {
+	Prompt
+	// 空の場合は翻訳キーprompt_confirmのメッセージ
+	ConfirmLabel string
+	// 空の場合は翻訳キーprompt_cancelのメッセージ
+	CancelLabel string
+	// 確認ボタンを赤くする
+	Danger bool
+}
+
+// 選択メニュー
+type SelectPrompt struct {
+	Prompt
+	Placeholder string
+	Options     []discord.StringSelectMenuOption
+	// 0の場合は1
+	MinValues int
+	// 0の場合は1
+	MaxValues int
+}
+
+const (
+	promptConfirm = "confirm"
+	promptCancel  = "cancel"
+	promptSelect  = "select"
+)
+
+// 確認ダイアログを表示し、呼び出したユーザーの選択を待つ
+// 選択された後と期限切れの後はボタンを無効にする
+//
+// ハンダラの中から呼び出す
+// 操作はWaitForと同じくキューに入る前に渡されるが、イベントを同期的に処理している場合は受け取れないためASyncかStartDispatcherと併用すること
+func (h *Handler) Confirm(ctx context.Context, interaction PromptInteraction, prompt ConfirmPrompt) (bool, error) {
+	locale := interaction.Locale()
+	confirmLabel := prompt.ConfirmLabel
+	if confirmLabel == "" {
+		confirmLabel = translate.Message(locale, "prompt_confirm", translate.WithFallback("OK"))
+	}
+	cancelLabel := prompt.CancelLabel
+	if cancelLabel == "" {
+		cancelLabel = translate.Message(locale, "prompt_cancel", translate.WithFallback("キャンセル"))
+	}
+	event, err := h.runPrompt(ctx, interaction, prompt.Prompt, func(prefix string, disabled bool) []discord.ContainerComponent {
+		confirm := discord.NewPrimaryButton(confirmLabel, prefix+promptConfirm)
+		if prompt.Danger {
+			confirm = discord.NewDangerButton(confirmLabel, prefix+promptConfirm)
+		}
+		return []discord.ContainerComponent{
+			discord.NewActionRow(
+				confirm.WithDisabled(disabled),
+				discord.NewSecondaryButton(cancelLabel, prefix+promptCancel).WithDisabled(disabled),
+			),
+		}
+	})
+	if err != nil {
+		return false, err
+	}
+	return strings.HasSuffix(event.Data.CustomID(), promptConfirm), nil
+}
+
+// 選択メニューを表示し、呼び出したユーザーが選んだ値を返す
+// 選択された後と期限切れの後はメニューを無効にする
+//
+// ハンダラの中から呼び出す
+// 操作はWaitForと同じくキューに入る前に渡されるが、イベントを同期的に処理している場合は受け取れないためASyncかStartDispatcherと併用すること
+func (h *Handler) Select(ctx context.Context, interaction PromptInteraction, prompt SelectPrompt) ([]string, error) {
+	event, err := h.runPrompt(ctx, interaction, prompt.Prompt, func(prefix string, disabled bool) []discord.ContainerComponent {
+		menu := discord.NewStringSelectMenu(prefix+promptSelect, prompt.Placeholder, prompt.Options...)
+		if prompt.MinValues > 0 {
+			menu.MinValues = &prompt.MinValues
+		}
+		menu.MaxValues = prompt.MaxValues
+		menu.Disabled = disabled
+		return []discord.ContainerComponent{discord.NewActionRow(menu)}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return event.StringSelectMenuInteractionData().Values, nil
+}
+
+// プロンプトを操作したインタラクション
+type promptEvent interface {
+	bot.Event
+	Responder
+	User() discord.User
+	UpdateMessage(discord.MessageUpdate, ...rest.RequestOpt) error
+	DeferUpdateMessage(...rest.RequestOpt) error
+}
+
+// プロンプトを送り、呼び出したユーザーがコンポーネントを操作するのを待つ
+// componentsはカスタムIDの接頭辞と無効にするかどうかからコンポーネントを作成する
+func (h *Handler) runPrompt(ctx context.Context, interaction PromptInteraction, prompt Prompt, components func(prefix string, disabled bool) []discord.ContainerComponent) (*events.ComponentInteractionCreate, error) {
+	return awaitPrompt(ctx, h, interaction, prompt, components, func(event bot.Event, prefix string) (*events.ComponentInteractionCreate, bool) {
+		e, ok := event.(*events.ComponentInteractionCreate)
+		return e, ok && strings.HasPrefix(e.Data.CustomID(), prefix)
+	})
+}
+
+// runPromptの本体
+// matchは受け取ったイベントがプロンプトの操作かどうかを返す
+func awaitPrompt[E promptEvent](ctx context.Context, h *Handler, interaction PromptInteraction, prompt Prompt, components func(prefix string, disabled bool) []discord.ContainerComponent, match func(event bot.Event, prefix string) (E, bool)) (E, error) {
+	var zero E
+	tracker, ok := ctx.Value(ackTrackerKey{}).(*ackTracker)
+	if !ok {
+		return zero, ErrNoInteraction
+	}
+	timeout := prompt.Timeout
+	if timeout <= 0 {
+		timeout = DefaultPromptTimeout
+	}
+
+	// ハンダラのカスタムIDではないのでコンポーネントのハンダラには渡らない
+	prefix := "prompt:" + uuid.NewString() + ":"
+	userID := interaction.User().ID
+	selected := make(chan E, 1)
+	// 最初に受け取った呼び出したユーザーの操作を選択とし、それ以外には応答を待たずに返す
+	reply := func(e E, fn func() error) {
+		h.inFlight.Add(1)
+		go func() {
+			defer h.inFlight.Done()
+			if err := h.invoke("prompt", e, fn); err != nil {
+				h.Logger.Errorf("Failed to handle prompt interaction: %s", err)
+			}
+		}()
+	}
+	waiterID := uuid.New()
+	h.addWaiter(waiterID, waiter{
+		check: func(event bot.Event) bool {
+			_, ok := match(event, prefix)
+			return ok
+		},
+		deliver: func(event bot.Event) bool {
+			e, _ := match(event, prefix)
+			if e.User().ID != userID {
+				reply(e, func() error {
+					return h.replyErrorMessage(e, PromptNotOwnerMessage, true)
+				})
+				return true
+			}
+			select {
+			case selected <- e:
+			default:
+				// 既に選択されている
+				reply(e, func() error {
+					return e.DeferUpdateMessage()
+				})
+			}
+			return true
+		},
+	})
+	defer h.removeWaiter(waiterID)
+
+	message := prompt.Message
+	message.Components = components(prefix, false)
+	if prompt.Ephemeral {
+		message.Flags |= discord.MessageFlagEphemeral
+	}
+	update, err := tracker.send(message)
+	if err != nil {
+		return zero, err
+	}
+	tracker.convertResponses()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	disabled := components(prefix, true)
+	select {
+	case event := <-selected:
+		if err := event.UpdateMessage(discord.MessageUpdate{Components: &disabled}); err != nil {
+			return zero, err
+		}
+		return event, nil
+	case <-timer.C:
+		err = ErrPromptTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err := update(discord.MessageUpdate{Components: &disabled}); err != nil {
+		h.Logger.Errorf("Failed to disable prompt components: %s", err)
+	}
+	return zero, err
+}
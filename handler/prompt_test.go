@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/log"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/rest"
+)
+
+type testPromptInteraction struct{}
+
+func (testPromptInteraction) User() discord.User     { return discord.User{ID: 1} }
+func (testPromptInteraction) Locale() discord.Locale { return discord.LocaleJapanese }
+
+func TestPromptWithoutInteraction(t *testing.T) {
+	h := New(log.Default())
+	if _, err := h.Confirm(context.Background(), testPromptInteraction{}, ConfirmPrompt{}); err != ErrNoInteraction {
+		t.Errorf("expected ErrNoInteraction, got %v", err)
+	}
+	if len(h.waiters) != 0 {
+		t.Error("expected no waiter to be registered")
+	}
+	if AsError(ErrPromptTimeout).Kind != ErrorKindUser {
+		t.Error("expected prompt timeout to be a user error")
+	}
+}
+
+// 応答を記録するプロンプトの操作
+type testPromptEvent struct {
+	bot.Event
+	testInteraction
+	userID   snowflake.ID
+	customID string
+	calls    *testPromptCalls
+}
+
+type testPromptCalls struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (c *testPromptCalls) record(call string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, call)
+}
+
+func (c *testPromptCalls) Calls() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string{}, c.calls...)
+}
+
+func (e *testPromptEvent) User() discord.User     { return discord.User{ID: e.userID} }
+func (e *testPromptEvent) Locale() discord.Locale { return discord.LocaleJapanese }
+func (e *testPromptEvent) CreateMessage(discord.MessageCreate, ...rest.RequestOpt) error {
+	e.calls.record(e.customID + " create")
+	return nil
+}
+func (e *testPromptEvent) UpdateMessage(discord.MessageUpdate, ...rest.RequestOpt) error {
+	e.calls.record(e.customID + " update")
+	return nil
+}
+func (e *testPromptEvent) DeferUpdateMessage(...rest.RequestOpt) error {
+	e.calls.record(e.customID + " defer")
+	return nil
+}
+
+func matchTestPromptEvent(event bot.Event, prefix string) (*testPromptEvent, bool) {
+	e, ok := event.(*testPromptEvent)
+	return e, ok && strings.HasPrefix(e.customID, prefix)
+}
+
+type testPrompt struct {
+	h         *Handler
+	ctx       context.Context
+	responder *testResponder
+	rest      *testInteractionRest
+	prefix    chan string
+	mu        sync.Mutex
+	disabled  []bool
+}
+
+func newTestPrompt() *testPrompt {
+	responder := &testResponder{}
+	tracker, r := newTestAckTracker(responder)
+	return &testPrompt{
+		h:         New(log.Default()),
+		ctx:       withAckTracker(context.Background(), tracker),
+		responder: responder,
+		rest:      r,
+		prefix:    make(chan string, 1),
+	}
+}
+
+func (p *testPrompt) components(prefix string, disabled bool) []discord.ContainerComponent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.disabled) == 0 {
+		p.prefix <- prefix
+	}
+	p.disabled = append(p.disabled, disabled)
+	return []discord.ContainerComponent{discord.NewActionRow(discord.NewPrimaryButton("ok", prefix+"ok").WithDisabled(disabled))}
+}
+
+type testPromptResult struct {
+	event *testPromptEvent
+	err   error
+}
+
+func (p *testPrompt) run(timeout time.Duration) <-chan testPromptResult {
+	result := make(chan testPromptResult, 1)
+	go func() {
+		event, err := awaitPrompt(p.ctx, p.h, testPromptInteraction{}, Prompt{Timeout: timeout}, p.components, matchTestPromptEvent)
+		result <- testPromptResult{event: event, err: err}
+	}()
+	return result
+}
+
+func TestPromptSelection(t *testing.T) {
+	p := newTestPrompt()
+	result := p.run(time.Second)
+	prefix := <-p.prefix
+	waitWaiters(t, p.h, 1)
+
+	calls := &testPromptCalls{}
+	p.h.OnEvent(&testPromptEvent{userID: 2, customID: prefix + "other", calls: calls})
+	p.h.OnEvent(&testPromptEvent{userID: 1, customID: prefix + "first", calls: calls})
+	p.h.OnEvent(&testPromptEvent{userID: 1, customID: prefix + "second", calls: calls})
+	r := <-result
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if r.event.customID != prefix+"first" {
+		t.Errorf("expected the first choice to win, got %q", r.event.customID)
+	}
+	if err := p.h.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// プロンプトを送り、以降の応答はフォローアップにする
+	if responses := p.responder.Responses(); len(responses) != 1 || responses[0] != discord.InteractionResponseTypeCreateMessage {
+		t.Errorf("expected the prompt to be sent, got %v", responses)
+	}
+	if err := p.ctx.Value(ackTrackerKey{}).(*ackTracker).respond(discord.InteractionResponseTypeCreateMessage, discord.MessageCreate{}); err != nil {
+		t.Fatal(err)
+	}
+	if calls := p.rest.Calls(); len(calls) != 1 || calls[0] != "create followup" {
+		t.Errorf("expected later responses to be followups, got %q", calls)
+	}
+
+	// 二つ目の選択は選ばれた後に受け取れば応答だけを返し、待機の解除後なら無視される
+	got := slices.DeleteFunc(calls.Calls(), func(call string) bool {
+		return call == prefix+"second defer"
+	})
+	slices.Sort(got)
+	want := []string{prefix + "first update", prefix + "other create"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if !slices.Equal(p.disabled, []bool{false, true}) {
+		t.Errorf("expected the components to be disabled after the selection, got %v", p.disabled)
+	}
+	if len(p.h.waiters) != 0 {
+		t.Error("expected the waiter to be removed")
+	}
+}
+
+func TestPromptTimeout(t *testing.T) {
+	p := newTestPrompt()
+	if r := <-p.run(10 * time.Millisecond); r.err != ErrPromptTimeout {
+		t.Fatalf("expected ErrPromptTimeout, got %v", r.err)
+	}
+	if calls := p.rest.Calls(); len(calls) != 1 || calls[0] != "update original" {
+		t.Errorf("expected the prompt to be disabled, got %q", calls)
+	}
+	if !slices.Equal(p.disabled, []bool{false, true}) {
+		t.Errorf("expected disabled components, got %v", p.disabled)
+	}
+	if len(p.h.waiters) != 0 {
+		t.Error("expected the waiter to be removed")
+	}
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/rest"
+)
+
+// メッセージを作成して応答できるインタラクション
+type Responder interface {
+	ApplicationID() snowflake.ID
+	Token() string
+	Locale() discord.Locale
+	CreateMessage(discord.MessageCreate, ...rest.RequestOpt) error
+}
+
+// 翻訳キーからエラーメッセージを作成して応答する
+type ErrorMessageFunc func(interaction Responder, tr string, ephemeral bool, data ...any) error
+
+func (h *Handler) replyErrorMessage(interaction Responder, tr string, ephemeral bool, data ...any) error {
+	if h.ErrorMessage != nil {
+		return h.ErrorMessage(interaction, tr, ephemeral, data...)
+	}
+	return defaultErrorMessage(interaction, tr, ephemeral, data...)
+}
+
+func defaultErrorMessage(interaction Responder, tr string, ephemeral bool, data ...any) error {
+	var td any
+	if len(data) != 0 {
+		td = data[0]
+	}
+	var flags discord.MessageFlags
+	if ephemeral {
+		flags = discord.MessageFlagEphemeral
+	}
+	return interaction.CreateMessage(discord.MessageCreate{
+		Embeds: defaultErrorEmbeds(interaction.Locale(), tr, td),
+		Flags:  flags,
+	})
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-lib/v2/caches"
+	"github.com/sabafly/sabafly-lib/v2/handler/customid"
+)
+
+const (
+	// 既定の状態ストアで状態を保持する時間
+	DefaultStateTTL = InteractionTokenTimeout
+	// 状態が失われていた場合に返すエラーメッセージの翻訳キー
+	StateExpiredMessage = "interaction_expired"
+)
+
+// コンポーネントやモーダルの状態を保存するストア
+type StateStore interface {
+	Set(ctx context.Context, key uuid.UUID, state any) error
+	// 状態がない場合はokがfalseになる
+	Get(ctx context.Context, key uuid.UUID) (state any, ok bool, err error)
+	Delete(ctx context.Context, key uuid.UUID) error
+}
+
+// 状態が失われていたインタラクションに応答する
+type StateExpiredFunc func(ctx context.Context, interaction Responder) error
+
+type cacheStateStore struct {
+	cache *caches.CacheManager[any]
+}
+
+// CacheManagerに状態を保存するストアを作成する
+func NewCacheStateStore(ttl time.Duration) StateStore {
+	return &cacheStateStore{cache: caches.NewCacheManager[any](&ttl)}
+}
+
+func (s *cacheStateStore) Set(_ context.Context, key uuid.UUID, state any) error {
+	s.cache.Set(key.String(), state)
+	return nil
+}
+
+func (s *cacheStateStore) Get(_ context.Context, key uuid.UUID) (any, bool, error) {
+	state, err := s.cache.Get(key.String())
+	if err != nil {
+		return nil, false, nil
+	}
+	return state, true, nil
+}
+
+func (s *cacheStateStore) Delete(_ context.Context, key uuid.UUID) error {
+	s.cache.Delete(key.String())
+	return nil
+}
+
+type stateKey struct{}
+
+type stateValue struct {
+	key   uuid.UUID
+	state any
+}
+
+// 状態を保存し、状態を参照するカスタムIDを返す
+func (h *Handler) EncodeCustomIDWithState(ctx context.Context, b *customid.Builder, state any) (string, error) {
+	key := uuid.New()
+	id, err := b.State(key).Encode(h.CustomIDKey)
+	if err != nil {
+		return "", err
+	}
+	if err := h.StateStore.Set(ctx, key, state); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// ハンダラを呼び出したカスタムIDが参照する状態を返す
+func State[T any](ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(stateKey{}).(stateValue)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	state, ok := v.state.(T)
+	return state, ok
+}
+
+// ハンダラを呼び出したカスタムIDが参照する状態を更新する
+func (h *Handler) UpdateState(ctx context.Context, state any) error {
+	v, ok := ctx.Value(stateKey{}).(stateValue)
+	if !ok {
+		return fmt.Errorf("no state in context")
+	}
+	return h.StateStore.Set(ctx, v.key, state)
+}
+
+// ハンダラを呼び出したカスタムIDが参照する状態を削除する
+func (h *Handler) DeleteState(ctx context.Context) error {
+	v, ok := ctx.Value(stateKey{}).(stateValue)
+	if !ok {
+		return nil
+	}
+	return h.StateStore.Delete(ctx, v.key)
+}
+
+// カスタムIDが状態を参照している場合は読み込んでコンテキストに格納する
+// 状態が失われていたか読み込めなかった場合はokがfalseになり、応答済みになる
+func (h *Handler) loadState(ctx context.Context, id *customid.ID, interaction Responder) (context.Context, bool) {
+	key, ok := id.State()
+	if !ok {
+		return ctx, true
+	}
+	state, ok, err := h.StateStore.Get(ctx, key)
+	if err != nil {
+		h.Logger.Errorf("Failed to load state for \"%s:%s\" : %s", id.Name, id.Sub, err)
+		if err := interaction.CreateMessage(discord.MessageCreate{
+			Embeds: h.errorEmbeds(interaction.Locale(), Internal(err)),
+			Flags:  discord.MessageFlagEphemeral,
+		}); err != nil {
+			h.Logger.Errorf("Failed to reply state error for \"%s:%s\" : %s", id.Name, id.Sub, err)
+		}
+		return ctx, false
+	}
+	if !ok {
+		h.Logger.Debugf("状態が失われています %s:%s", id.Name, id.Sub)
+		if err := h.stateExpired(ctx, interaction); err != nil {
+			h.Logger.Errorf("Failed to respond to expired interaction for \"%s:%s\" : %s", id.Name, id.Sub, err)
+		}
+		return ctx, false
+	}
+	return context.WithValue(ctx, stateKey{}, stateValue{key: key, state: state}), true
+}
+
+func (h *Handler) stateExpired(ctx context.Context, interaction Responder) error {
+	if h.StateExpired != nil {
+		return h.StateExpired(ctx, interaction)
+	}
+	return h.replyErrorMessage(interaction, StateExpiredMessage, true)
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/disgoorg/log"
+	"github.com/google/uuid"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/rest"
+	"github.com/sabafly/sabafly-lib/v2/handler/customid"
+)
+
+type pageState struct {
+	Pages []string
+	Index int
+}
+
+func TestState(t *testing.T) {
+	h := New(log.Default())
+	h.CustomIDKey = []byte("secret")
+	ctx := context.Background()
+	s, err := h.EncodeCustomIDWithState(ctx, Component{Name: "pager"}.CustomID("next"), pageState{Pages: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := customid.Decode(s, h.CustomIDKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, ok := h.loadState(ctx, id, nil)
+	if !ok {
+		t.Fatal("expected state to be loaded")
+	}
+	state, ok := State[pageState](ctx)
+	if !ok || len(state.Pages) != 2 {
+		t.Fatalf("unexpected state %+v", state)
+	}
+	state.Index = 1
+	if err := h.UpdateState(ctx, state); err != nil {
+		t.Fatal(err)
+	}
+	ctx, _ = h.loadState(context.Background(), id, nil)
+	if state, _ := State[pageState](ctx); state.Index != 1 {
+		t.Errorf("expected updated state, got %+v", state)
+	}
+
+	if err := h.DeleteState(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var expired bool
+	h.StateExpired = func(context.Context, Responder) error {
+		expired = true
+		return nil
+	}
+	if _, ok := h.loadState(context.Background(), id, nil); ok || !expired {
+		t.Error("expected expired response")
+	}
+}
+
+type failingStateStore struct{}
+
+func (failingStateStore) Set(context.Context, uuid.UUID, any) error { return nil }
+func (failingStateStore) Get(context.Context, uuid.UUID) (any, bool, error) {
+	return nil, false, errors.New("unavailable")
+}
+func (failingStateStore) Delete(context.Context, uuid.UUID) error { return nil }
+
+type testStateInteraction struct {
+	testInteraction
+	messages []discord.MessageCreate
+}
+
+func (*testStateInteraction) Locale() discord.Locale { return discord.LocaleJapanese }
+func (i *testStateInteraction) CreateMessage(message discord.MessageCreate, _ ...rest.RequestOpt) error {
+	i.messages = append(i.messages, message)
+	return nil
+}
+
+func TestStateStoreError(t *testing.T) {
+	h := New(log.Default())
+	h.StateStore = failingStateStore{}
+	h.ErrorEmbeds = func(_ discord.Locale, err *Error) []discord.Embed {
+		return []discord.Embed{{Title: err.Key}}
+	}
+	id, err := customid.Decode(customid.New("pager", "next").State(uuid.New()).MustEncode(nil), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interaction := &testStateInteraction{}
+	if _, ok := h.loadState(context.Background(), id, interaction); ok {
+		t.Fatal("expected state not to be loaded")
+	}
+	if len(interaction.messages) != 1 || interaction.messages[0].Embeds[0].Title != InternalErrorMessage || interaction.messages[0].Flags != discord.MessageFlagEphemeral {
+		t.Errorf("expected an ephemeral internal error reply, got %+v", interaction.messages)
+	}
+}
@@ -0,0 +1,495 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/rest"
+)
+
+type SyncOptions struct {
+	// trueの場合は変更を適用せずに差分だけを返す
+	DryRun bool
+	// 前回同期したコマンドのハッシュを保存するファイル
+	// 空でない場合、ハッシュが一致する範囲はAPIを呼ばずに省略する
+	HashFile string
+}
+
+type SyncAction string
+
+const (
+	SyncActionCreate SyncAction = "create"
+	SyncActionUpdate SyncAction = "update"
+	SyncActionDelete SyncAction = "delete"
+)
+
+type SyncChange struct {
+	Action SyncAction
+	Type   discord.ApplicationCommandType
+	Name   string
+	// 更新と削除の場合の既存のコマンドのID
+	ID snowflake.ID
+}
+
+// グローバルまたはギルドひとつ分の同期結果
+type SyncResult struct {
+	// nilの場合はグローバルコマンド
+	GuildID   *snowflake.ID
+	Changes   []SyncChange
+	Unchanged int
+	// ハッシュが一致したため省略された
+	Skipped bool
+}
+
+type SyncReport []SyncResult
+
+func (r SyncReport) String() string {
+	var b strings.Builder
+	for _, result := range r {
+		scope := scopeName(result.GuildID)
+		if result.Skipped {
+			fmt.Fprintf(&b, "%s: skipped (unchanged since last sync)\n", scope)
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %d changes, %d unchanged\n", scope, len(result.Changes), result.Unchanged)
+		for _, change := range result.Changes {
+			fmt.Fprintf(&b, "  %s %s\n", change.Action, change.Name)
+		}
+	}
+	return b.String()
+}
+
+type syncScope struct {
+	guildID  *snowflake.ID
+	commands []discord.ApplicationCommandCreate
+}
+
+func (s syncScope) key(applicationID snowflake.ID) string {
+	if s.guildID == nil {
+		return applicationID.String() + "/global"
+	}
+	return applicationID.String() + "/" + s.guildID.String()
+}
+
+// 同期する範囲ごとにコマンドを振り分ける
+// DevOnlyのコマンドはDevGuildIDに、ギルドが限定されたコマンドは該当するギルドに、
+// それ以外はguildIDsか、空の場合はグローバルに登録する
+// GuildFilterを持つコマンドがある場合はknownGuildsの全てのギルドを対象にする
+// 前回までに同期したギルドも対象にし、不要になったコマンドを削除する
+func (h *Handler) syncScopes(guildIDs []snowflake.ID, knownGuilds func() []snowflake.ID) []syncScope {
+	h.mu.RLock()
+	devGuildIDs := append([]snowflake.ID{}, h.DevGuildID...)
+	synced := make([]snowflake.ID, 0, len(h.syncedGuilds))
+	for id := range h.syncedGuilds {
+		synced = append(synced, id)
+	}
+	h.mu.RUnlock()
+	slices.Sort(synced)
+	commands := h.commands()
+
+	var targets []snowflake.ID
+	addTarget := func(ids ...snowflake.ID) {
+		for _, id := range ids {
+			if !slices.Contains(targets, id) {
+				targets = append(targets, id)
+			}
+		}
+	}
+	addTarget(guildIDs...)
+	var hasDev, hasFilter bool
+	for _, command := range commands {
+		hasDev = hasDev || command.DevOnly
+		hasFilter = hasFilter || command.GuildFilter != nil
+		if !command.DevOnly {
+			addTarget(command.GuildIDs...)
+		}
+	}
+	if hasDev {
+		addTarget(devGuildIDs...)
+	}
+	if hasFilter && knownGuilds != nil {
+		addTarget(knownGuilds()...)
+	}
+	addTarget(synced...)
+
+	var scopes []syncScope
+	if len(guildIDs) == 0 {
+		global := syncScope{}
+		for _, command := range commands {
+			if !command.DevOnly && !command.guildScoped() {
+				global.commands = append(global.commands, command.Create)
+			}
+		}
+		scopes = append(scopes, global)
+	}
+	for _, guildID := range targets {
+		scopes = append(scopes, guildScope(commands, guildID, slices.Contains(devGuildIDs, guildID), slices.Contains(guildIDs, guildID)))
+	}
+	return scopes
+}
+
+// ギルドに登録するコマンドを集める
+// allがtrueの場合はギルドが限定されていないコマンドも含める
+func guildScope(commands []Command, guildID snowflake.ID, dev, all bool) syncScope {
+	scope := syncScope{guildID: &guildID}
+	for _, command := range commands {
+		var ok bool
+		switch {
+		case command.DevOnly:
+			ok = dev
+		case command.guildScoped():
+			ok = command.enabledIn(guildID)
+		default:
+			ok = all
+		}
+		if ok {
+			scope.commands = append(scope.commands, command.Create)
+		}
+	}
+	return scope
+}
+
+func cachedGuildIDs(client bot.Client) func() []snowflake.ID {
+	return func() []snowflake.ID {
+		var ids []snowflake.ID
+		client.Caches().GuildsForEach(func(guild discord.Guild) {
+			ids = append(ids, guild.ID)
+		})
+		return ids
+	}
+}
+
+// 登録されているコマンドとDiscord上のコマンドの差分を取り、変更があったものだけを作成、更新、削除する
+func (h *Handler) Sync(ctx context.Context, client bot.Client, opts SyncOptions, guildIDs ...snowflake.ID) (SyncReport, error) {
+	return h.sync(ctx, client, opts, func() []syncScope {
+		return h.syncScopes(guildIDs, cachedGuildIDs(client))
+	})
+}
+
+// ひとつのギルドのコマンドだけを再同期する
+// ギルドが限定されていないコマンドはグローバルに登録されているものとして扱う
+func (h *Handler) SyncGuild(ctx context.Context, client bot.Client, opts SyncOptions, guildID snowflake.ID) (SyncResult, error) {
+	h.mu.RLock()
+	dev := slices.Contains(h.DevGuildID, guildID)
+	h.mu.RUnlock()
+	report, err := h.sync(ctx, client, opts, func() []syncScope {
+		return []syncScope{guildScope(h.commands(), guildID, dev, false)}
+	})
+	return report[0], err
+}
+
+func (h *Handler) sync(ctx context.Context, client bot.Client, opts SyncOptions, scopes func() []syncScope) (SyncReport, error) {
+	h.syncMu.Lock()
+	defer h.syncMu.Unlock()
+
+	hashes, err := readSyncHashes(opts.HashFile)
+	if err != nil {
+		h.Logger.Warnf("Failed to read command hash file: %s", err)
+		hashes = map[string]string{}
+	}
+	// 再起動した後もハッシュファイルから前回同期したギルドを引き継ぐ
+	h.mu.Lock()
+	for _, guildID := range hashedGuildIDs(hashes, client.ApplicationID()) {
+		h.syncedGuilds[guildID] = struct{}{}
+	}
+	h.mu.Unlock()
+
+	var (
+		report SyncReport
+		errs   []error
+		dirty  bool
+	)
+	for _, scope := range scopes() {
+		result, hash, err := h.syncScope(ctx, client, scope, hashes[scope.key(client.ApplicationID())], opts.DryRun)
+		report = append(report, result)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !opts.DryRun && scope.guildID != nil {
+			h.mu.Lock()
+			if len(scope.commands) == 0 {
+				// コマンドが残っていないギルドは次回から対象にしない
+				delete(h.syncedGuilds, *scope.guildID)
+			} else {
+				h.syncedGuilds[*scope.guildID] = struct{}{}
+			}
+			h.mu.Unlock()
+			// 空のギルドのハッシュを残すと次回は同期を飛ばした上でハッシュを消すため、毎回の取得と省略が交互になる
+			if len(scope.commands) == 0 {
+				if _, ok := hashes[scope.key(client.ApplicationID())]; ok {
+					delete(hashes, scope.key(client.ApplicationID()))
+					dirty = true
+				}
+				continue
+			}
+		}
+		if !opts.DryRun && !result.Skipped {
+			hashes[scope.key(client.ApplicationID())] = hash
+			dirty = true
+		}
+	}
+	if dirty && opts.HashFile != "" {
+		if err := writeSyncHashes(opts.HashFile, hashes); err != nil {
+			errs = append(errs, fmt.Errorf("failed to write command hash file: %w", err))
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+func (h *Handler) syncScope(ctx context.Context, client bot.Client, scope syncScope, lastHash string, dryRun bool) (SyncResult, string, error) {
+	result := SyncResult{GuildID: scope.guildID}
+	desired := map[string]syncCommand{}
+	for _, create := range scope.commands {
+		spec, err := normalizeCommand(create.Type(), create, scope.guildID != nil)
+		if err != nil {
+			return result, "", err
+		}
+		desired[commandKey(create.Type(), create.CommandName())] = syncCommand{commandType: create.Type(), name: create.CommandName(), create: create, spec: spec}
+	}
+	hash := hashCommands(desired)
+	if lastHash != "" && lastHash == hash {
+		result.Skipped = true
+		return result, hash, nil
+	}
+
+	applicationID := client.ApplicationID()
+	opt := rest.WithCtx(ctx)
+	var existing []discord.ApplicationCommand
+	var err error
+	if scope.guildID == nil {
+		existing, err = client.Rest().GetGlobalCommands(applicationID, true, opt)
+	} else {
+		existing, err = client.Rest().GetGuildCommands(applicationID, *scope.guildID, true, opt)
+	}
+	if err != nil {
+		return result, "", fmt.Errorf("failed to get commands for %s: %w", scopeName(scope.guildID), err)
+	}
+	remote := map[string]syncCommand{}
+	for _, command := range existing {
+		spec, err := normalizeCommand(command.Type(), command, scope.guildID != nil)
+		if err != nil {
+			return result, "", err
+		}
+		remote[commandKey(command.Type(), command.Name())] = syncCommand{id: command.ID(), commandType: command.Type(), name: command.Name(), spec: spec}
+	}
+
+	result.Changes, result.Unchanged = diffCommands(desired, remote)
+	if dryRun {
+		return result, hash, nil
+	}
+
+	var errs []error
+	for _, change := range result.Changes {
+		key := commandKey(change.Type, change.Name)
+		switch change.Action {
+		case SyncActionCreate, SyncActionUpdate:
+			// 同じ名前のコマンドを作成すると既存のコマンドがIDを保ったまま上書きされる
+			if scope.guildID == nil {
+				_, err = client.Rest().CreateGlobalCommand(applicationID, desired[key].create, opt)
+			} else {
+				_, err = client.Rest().CreateGuildCommand(applicationID, *scope.guildID, desired[key].create, opt)
+			}
+		case SyncActionDelete:
+			if scope.guildID == nil {
+				err = client.Rest().DeleteGlobalCommand(applicationID, change.ID, opt)
+			} else {
+				err = client.Rest().DeleteGuildCommand(applicationID, *scope.guildID, change.ID, opt)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to %s command %q for %s: %w", change.Action, change.Name, scopeName(scope.guildID), err))
+		}
+	}
+	return result, hash, errors.Join(errs...)
+}
+
+type syncCommand struct {
+	id          snowflake.ID
+	commandType discord.ApplicationCommandType
+	name        string
+	create      discord.ApplicationCommandCreate
+	spec        string
+}
+
+func commandKey(t discord.ApplicationCommandType, name string) string {
+	return fmt.Sprintf("%d:%s", t, name)
+}
+
+func scopeName(guildID *snowflake.ID) string {
+	if guildID == nil {
+		return "global"
+	}
+	return "guild " + guildID.String()
+}
+
+func diffCommands(desired, remote map[string]syncCommand) (changes []SyncChange, unchanged int) {
+	for _, key := range sortedKeys(desired) {
+		command := desired[key]
+		r, ok := remote[key]
+		switch {
+		case !ok:
+			changes = append(changes, SyncChange{Action: SyncActionCreate, Type: command.commandType, Name: command.name})
+		case r.spec != command.spec:
+			changes = append(changes, SyncChange{Action: SyncActionUpdate, Type: command.commandType, Name: command.name, ID: r.id})
+		default:
+			unchanged++
+		}
+	}
+	for _, key := range sortedKeys(remote) {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		command := remote[key]
+		changes = append(changes, SyncChange{Action: SyncActionDelete, Type: command.commandType, Name: command.name, ID: command.id})
+	}
+	return changes, unchanged
+}
+
+// 作成データと取得したコマンドを比較できる形にする
+// 名前、説明、オプション、ローカライズ、既定の権限、DMでの使用可否を比較の対象とする
+func normalizeCommand(t discord.ApplicationCommandType, command any, guild bool) (string, error) {
+	data, err := json.Marshal(command)
+	if err != nil {
+		return "", err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return "", err
+	}
+	for _, key := range []string{"id", "application_id", "guild_id", "version", "name_localized", "description_localized"} {
+		delete(m, key)
+	}
+	m["type"] = float64(t)
+	if guild {
+		// ギルドコマンドではdm_permissionは無視される
+		delete(m, "dm_permission")
+	} else if v, ok := m["dm_permission"]; !ok || v == nil {
+		m["dm_permission"] = true
+	}
+	// 権限の未設定はnullか省略として返され、pruneJSONで取り除かれる
+	// "0"は管理者だけが使えるという意味なので残す
+	data, err = json.Marshal(pruneJSON(m))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// 既定値と同じ意味のnull、false、空文字列、空の配列とオブジェクトを取り除く
+func pruneJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			value = pruneJSON(value)
+			if isEmptyJSON(value) {
+				delete(v, key)
+				continue
+			}
+			v[key] = value
+		}
+		return v
+	case []any:
+		for i, value := range v {
+			v[i] = pruneJSON(value)
+		}
+		return v
+	}
+	return v
+}
+
+func isEmptyJSON(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case map[string]any:
+		return len(v) == 0
+	case []any:
+		return len(v) == 0
+	}
+	return false
+}
+
+func hashCommands(commands map[string]syncCommand) string {
+	hash := sha256.New()
+	for _, key := range sortedKeys(commands) {
+		hash.Write([]byte(commands[key].spec))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// ハッシュファイルに記録されたアプリケーションのギルドのIDを返す
+func hashedGuildIDs(hashes map[string]string, applicationID snowflake.ID) []snowflake.ID {
+	var ids []snowflake.ID
+	for key := range hashes {
+		scope, ok := strings.CutPrefix(key, applicationID.String()+"/")
+		if !ok {
+			continue
+		}
+		if id, err := snowflake.Parse(scope); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func readSyncHashes(path string) (map[string]string, error) {
+	hashes := map[string]string{}
+	if path == "" {
+		return hashes, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return hashes, nil
+	}
+	if err != nil {
+		return hashes, err
+	}
+	if err := json.Unmarshal(data, &hashes); err != nil {
+		return map[string]string{}, err
+	}
+	return hashes, nil
+}
+
+func writeSyncHashes(path string, hashes map[string]string) error {
+	data, err := json.MarshalIndent(hashes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// 登録されているコマンドをSyncOptionsに従って同期する
+func (h *Handler) SyncCommands(client bot.Client, guildIDs ...snowflake.ID) {
+	report, err := h.Sync(h.baseContext(), client, h.SyncOptions, guildIDs...)
+	for _, result := range report {
+		switch {
+		case result.Skipped:
+			h.Logger.Infof("Skipped syncing %s commands: unchanged", scopeName(result.GuildID))
+		case h.SyncOptions.DryRun:
+			for _, change := range result.Changes {
+				h.Logger.Infof("[dry run] %s %s command %q", change.Action, scopeName(result.GuildID), change.Name)
+			}
+		default:
+			h.Logger.Infof("Synced %s commands: %d changed, %d unchanged", scopeName(result.GuildID), len(result.Changes), result.Unchanged)
+		}
+	}
+	if err != nil {
+		h.Logger.Errorf("Failed to sync commands: %s", err)
+	}
+}
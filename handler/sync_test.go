@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/disgoorg/json"
+	"github.com/disgoorg/log"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/rest"
+)
+
+type testSyncClient struct {
+	bot.Client
+	rest *testSyncRest
+}
+
+func (c *testSyncClient) ApplicationID() snowflake.ID { return 1 }
+func (c *testSyncClient) Rest() rest.Rest             { return c.rest }
+
+// ギルドのコマンドの取得だけを数える
+type testSyncRest struct {
+	rest.Rest
+	gets int
+}
+
+func (r *testSyncRest) GetGuildCommands(snowflake.ID, snowflake.ID, bool, ...rest.RequestOpt) ([]discord.ApplicationCommand, error) {
+	r.gets++
+	return nil, nil
+}
+
+func syncCommands(t *testing.T, guild bool, creates ...discord.ApplicationCommandCreate) map[string]syncCommand {
+	t.Helper()
+	commands := map[string]syncCommand{}
+	for i, create := range creates {
+		spec, err := normalizeCommand(create.Type(), create, guild)
+		if err != nil {
+			t.Fatal(err)
+		}
+		commands[commandKey(create.Type(), create.CommandName())] = syncCommand{
+			id:          snowflake.ID(i + 1),
+			commandType: create.Type(),
+			name:        create.CommandName(),
+			create:      create,
+			spec:        spec,
+		}
+	}
+	return commands
+}
+
+func TestDiffCommands(t *testing.T) {
+	remote := syncCommands(t, false,
+		discord.SlashCommandCreate{Name: "ping", Description: "ping", DMPermission: json.Ptr(true)},
+		discord.SlashCommandCreate{Name: "role", Description: "role"},
+		discord.SlashCommandCreate{Name: "old", Description: "old"},
+	)
+	desired := syncCommands(t, false,
+		discord.SlashCommandCreate{Name: "ping", Description: "ping"},
+		discord.SlashCommandCreate{Name: "role", Description: "role", DefaultMemberPermissions: json.NewNullablePtr(discord.PermissionManageGuild)},
+		discord.SlashCommandCreate{Name: "new", Description: "new"},
+	)
+	changes, unchanged := diffCommands(desired, remote)
+	if unchanged != 1 {
+		t.Errorf("expected 1 unchanged command, got %d", unchanged)
+	}
+	want := map[string]SyncAction{"new": SyncActionCreate, "role": SyncActionUpdate, "old": SyncActionDelete}
+	if len(changes) != len(want) {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+	for _, change := range changes {
+		if want[change.Name] != change.Action {
+			t.Errorf("expected %s for %q, got %s", want[change.Name], change.Name, change.Action)
+		}
+		if change.Action != SyncActionCreate && change.ID == 0 {
+			t.Errorf("expected id for %q", change.Name)
+		}
+	}
+}
+
+func TestDiffCommandsAdminOnly(t *testing.T) {
+	remote := syncCommands(t, false, discord.SlashCommandCreate{Name: "ban", Description: "ban"})
+	desired := syncCommands(t, false, discord.SlashCommandCreate{Name: "ban", Description: "ban", DefaultMemberPermissions: json.NewNullablePtr(discord.Permissions(0))})
+	changes, _ := diffCommands(desired, remote)
+	if len(changes) != 1 || changes[0].Action != SyncActionUpdate {
+		t.Fatalf("expected admin only permissions to update the command, got %+v", changes)
+	}
+	changes, unchanged := diffCommands(desired, desired)
+	if len(changes) != 0 || unchanged != 1 {
+		t.Errorf("expected admin only permissions to stay unchanged, got %+v", changes)
+	}
+}
+
+func TestSyncScopes(t *testing.T) {
+	h := New(log.Default())
+	h.DevGuildID = []snowflake.ID{1}
+	h.AddCommands(
+		Command{Create: discord.SlashCommandCreate{Name: "ping"}},
+		Command{Create: discord.SlashCommandCreate{Name: "debug"}, DevOnly: true},
+	)
+	scopes := h.syncScopes(nil, nil)
+	if len(scopes) != 2 || scopes[0].guildID != nil || len(scopes[0].commands) != 1 || len(scopes[1].commands) != 1 {
+		t.Fatalf("unexpected scopes %+v", scopes)
+	}
+	scopes = h.syncScopes([]snowflake.ID{1, 2}, nil)
+	if len(scopes) != 2 || len(scopes[0].commands) != 2 || len(scopes[1].commands) != 1 {
+		t.Fatalf("unexpected scopes %+v", scopes)
+	}
+}
+
+func TestSyncScopesGuildFilter(t *testing.T) {
+	h := New(log.Default())
+	premium := map[snowflake.ID]bool{2: true}
+	h.AddCommands(
+		Command{Create: discord.SlashCommandCreate{Name: "ping"}},
+		Command{Create: discord.SlashCommandCreate{Name: "beta"}, GuildIDs: []snowflake.ID{1}},
+		Command{Create: discord.SlashCommandCreate{Name: "premium"}, GuildFilter: func(guildID snowflake.ID) bool {
+			return premium[guildID]
+		}},
+	)
+	scopes := h.syncScopes(nil, func() []snowflake.ID { return []snowflake.ID{1, 2, 3} })
+	want := map[string][]string{"global": {"ping"}, "guild 1": {"beta"}, "guild 2": {"premium"}, "guild 3": nil}
+	if len(scopes) != len(want) {
+		t.Fatalf("unexpected scopes %+v", scopes)
+	}
+	for _, scope := range scopes {
+		var names []string
+		for _, create := range scope.commands {
+			names = append(names, create.CommandName())
+		}
+		if !slices.Equal(names, want[scopeName(scope.guildID)]) {
+			t.Errorf("%s: expected %v, got %v", scopeName(scope.guildID), want[scopeName(scope.guildID)], names)
+		}
+	}
+}
+
+func TestSyncScopesPreviousGuilds(t *testing.T) {
+	h := New(log.Default())
+	h.AddCommands(Command{Create: discord.SlashCommandCreate{Name: "premium"}, GuildFilter: func(guildID snowflake.ID) bool {
+		return guildID == 4
+	}})
+	for _, id := range hashedGuildIDs(map[string]string{"10/global": "", "10/5": "", "11/6": ""}, 10) {
+		h.syncedGuilds[id] = struct{}{}
+	}
+	// キャッシュにないギルドも明示したものと前回同期したものは対象にする
+	scopes := h.syncScopes([]snowflake.ID{4}, func() []snowflake.ID { return nil })
+	want := map[string][]string{"guild 4": {"premium"}, "guild 5": nil}
+	if len(scopes) != len(want) {
+		t.Fatalf("unexpected scopes %+v", scopes)
+	}
+	for _, scope := range scopes {
+		var names []string
+		for _, create := range scope.commands {
+			names = append(names, create.CommandName())
+		}
+		if !slices.Equal(names, want[scopeName(scope.guildID)]) {
+			t.Errorf("%s: expected %v, got %v", scopeName(scope.guildID), want[scopeName(scope.guildID)], names)
+		}
+	}
+}
+
+func TestSyncEmptyGuild(t *testing.T) {
+	h := New(log.Default())
+	client := &testSyncClient{rest: &testSyncRest{}}
+	opts := SyncOptions{HashFile: filepath.Join(t.TempDir(), "hashes.json")}
+	// コマンドのないギルドは毎回確認し、ハッシュを保存しない
+	for i := 1; i <= 3; i++ {
+		if _, err := h.SyncGuild(context.Background(), client, opts, 2); err != nil {
+			t.Fatal(err)
+		}
+		if client.rest.gets != i {
+			t.Fatalf("run %d: expected %d requests, got %d", i, i, client.rest.gets)
+		}
+		hashes, err := readSyncHashes(opts.HashFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hashes) != 0 {
+			t.Fatalf("run %d: expected no stored hash, got %v", i, hashes)
+		}
+	}
+}
@@ -0,0 +1,524 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/disgoorg/log"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+)
+
+type (
+	TextCommandHandler func(ctx context.Context, event *events.GuildMessageCreate, args TextArgs) error
+	// matchはPattern.FindStringSubmatchの結果
+	TextTriggerHandler func(ctx context.Context, event *events.GuildMessageCreate, match []string) error
+)
+
+// テキストコマンドの引数の種類
+type TextArgType int
+
+const (
+	TextArgString TextArgType = iota
+	TextArgInt
+	TextArgSnowflake
+	// メンションまたはID
+	TextArgUser
+	// メンションまたはID
+	TextArgRole
+	// メンションまたはID
+	TextArgChannel
+	// 1h30m、2d、1w のような期間
+	TextArgDuration
+	// 残りの文字列すべて
+	// 最後の引数にのみ指定できる
+	TextArgRest
+)
+
+type TextArg struct {
+	Name     string
+	Type     TextArgType
+	Optional bool
+}
+
+// プレフィックスで始まるメッセージで呼び出されるコマンド
+type TextCommand struct {
+	Name        string
+	Aliases     []string
+	Description string
+	Args        []TextArg
+	Check       Check[*events.GuildMessageCreate]
+	Handler     TextCommandHandler
+	// ヘルプに表示しない
+	Hidden bool
+}
+
+// 引数の使い方を返す
+func (c TextCommand) Usage() string {
+	var b strings.Builder
+	b.WriteString(c.Name)
+	for _, arg := range c.Args {
+		name := arg.Name
+		if arg.Type == TextArgRest {
+			name += "..."
+		}
+		if arg.Optional {
+			b.WriteString(" [" + name + "]")
+		} else {
+			b.WriteString(" <" + name + ">")
+		}
+	}
+	return b.String()
+}
+
+// 正規表現に一致したメッセージで呼び出されるハンダラ
+// コマンドとして処理されなかったメッセージにのみ使われる
+type TextTrigger struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Check   Check[*events.GuildMessageCreate]
+	Handler TextTriggerHandler
+}
+
+// 解析済みのテキストコマンドの引数
+type TextArgs struct {
+	// 使われたプレフィックス
+	Prefix string
+	// 使われたコマンド名またはエイリアス
+	Command string
+	// コマンド名より後のトークン
+	Raw []string
+
+	values map[string]any
+}
+
+func (a TextArgs) Has(name string) bool {
+	_, ok := a.values[name]
+	return ok
+}
+
+func (a TextArgs) String(name string) string {
+	v, _ := a.values[name].(string)
+	return v
+}
+
+func (a TextArgs) Int(name string) int {
+	v, _ := a.values[name].(int)
+	return v
+}
+
+// Snowflake、User、Role、Channelの引数を返す
+func (a TextArgs) Snowflake(name string) snowflake.ID {
+	v, _ := a.values[name].(snowflake.ID)
+	return v
+}
+
+func (a TextArgs) Duration(name string) time.Duration {
+	v, _ := a.values[name].(time.Duration)
+	return v
+}
+
+// テキストコマンドのルーター
+// Messageで返すハンダラをHandlerに登録して使う
+type TextRouter struct {
+	mu       sync.RWMutex
+	commands map[string]*TextCommand
+	list     []*TextCommand
+	triggers []TextTrigger
+
+	Logger log.Logger
+	// 既定のプレフィックス
+	Prefix string
+	// ギルドごとのプレフィックスを返す
+	// nilまたは空の場合はPrefixを使う
+	GuildPrefixes func(guildID snowflake.ID) []string
+	// ボットへのメンションをプレフィックスとして扱う
+	Mention bool
+	// コマンド名の大文字と小文字を区別しない
+	CaseInsensitive bool
+	// ボットのメッセージも処理する
+	AllowBots bool
+	// エラーメッセージの言語
+	Locale discord.Locale
+	// ハンダラが返したエラーを処理する
+	// 処理されなかったエラーはエラーメッセージとして返信する
+	OnError ErrorHandler
+}
+
+func NewTextRouter(logger log.Logger, prefix string) *TextRouter {
+	return &TextRouter{
+		commands: map[string]*TextCommand{},
+		Logger:   logger,
+		Prefix:   prefix,
+	}
+}
+
+func (r *TextRouter) key(name string) string {
+	if r.CaseInsensitive {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// コマンドを登録する
+// 名前やエイリアスが重複している場合はエラーを返し、何も登録しない
+func (r *TextRouter) AddCommands(commands ...TextCommand) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	added := map[string]struct{}{}
+	for _, c := range commands {
+		if c.Name == "" {
+			return errors.New("text command name is empty")
+		}
+		for i, arg := range c.Args {
+			if arg.Type == TextArgRest && i != len(c.Args)-1 {
+				return fmt.Errorf("text command %q: rest argument %q must be last", c.Name, arg.Name)
+			}
+		}
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			key := r.key(name)
+			if _, ok := r.commands[key]; ok {
+				return fmt.Errorf("text command %q is already registered", name)
+			}
+			if _, ok := added[key]; ok {
+				return fmt.Errorf("text command %q is duplicated", name)
+			}
+			added[key] = struct{}{}
+		}
+	}
+	for _, c := range commands {
+		c := c
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			r.commands[r.key(name)] = &c
+		}
+		r.list = append(r.list, &c)
+	}
+	return nil
+}
+
+func (r *TextRouter) AddTriggers(triggers ...TextTrigger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.triggers = append(r.triggers, triggers...)
+}
+
+func (r *TextRouter) command(name string) (*TextCommand, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.commands[r.key(name)]
+	return c, ok
+}
+
+// ギルドで使えるプレフィックスを長い順に返す
+func (r *TextRouter) prefixes(guildID, selfID snowflake.ID) []string {
+	var prefixes []string
+	if r.GuildPrefixes != nil {
+		prefixes = append(prefixes, r.GuildPrefixes(guildID)...)
+	}
+	if len(prefixes) == 0 && r.Prefix != "" {
+		prefixes = append(prefixes, r.Prefix)
+	}
+	if r.Mention && selfID != 0 {
+		prefixes = append(prefixes, "<@"+selfID.String()+">", "<@!"+selfID.String()+">")
+	}
+	sort.SliceStable(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+	return prefixes
+}
+
+// ルーターをメッセージハンダラにする
+func (r *TextRouter) Message() Message {
+	return Message{
+		ContextHandler: func(ctx context.Context, event *events.GuildMessageCreate) error {
+			if err := r.route(ctx, event, event.Client().ID()); err != nil {
+				r.handleError(ctx, event, err)
+			}
+			return nil
+		},
+	}
+}
+
+// メッセージをコマンドまたはトリガーに振り分ける
+func (r *TextRouter) route(ctx context.Context, event *events.GuildMessageCreate, selfID snowflake.ID) error {
+	if event.Message.Author.Bot && !r.AllowBots {
+		return nil
+	}
+	content := event.Message.Content
+	for _, prefix := range r.prefixes(event.GuildID, selfID) {
+		if !strings.HasPrefix(content, prefix) {
+			continue
+		}
+		tokens := tokenize(content[len(prefix):])
+		if len(tokens) == 0 {
+			break
+		}
+		c, ok := r.command(tokens[0].value)
+		if !ok {
+			break
+		}
+		if r := c.Check.Run(event); !r.Allowed {
+			if r.Reason == "" {
+				return nil
+			}
+			return PermissionDenied(r.Reason, r.Data)
+		}
+		args, err := parseTextArgs(content[len(prefix):], c.Args, tokens[1:])
+		if err != nil {
+			return err
+		}
+		args.Prefix = prefix
+		args.Command = tokens[0].value
+		r.Logger.Debugf("テキストコマンド %s", c.Name)
+		if c.Handler == nil {
+			return nil
+		}
+		return c.Handler(ctx, event, args)
+	}
+
+	r.mu.RLock()
+	triggers := append([]TextTrigger{}, r.triggers...)
+	r.mu.RUnlock()
+	for _, trigger := range triggers {
+		if trigger.Pattern == nil || trigger.Handler == nil {
+			continue
+		}
+		match := trigger.Pattern.FindStringSubmatch(content)
+		if match == nil {
+			continue
+		}
+		if !trigger.Check.Run(event).Allowed {
+			continue
+		}
+		r.Logger.Debugf("テキストトリガー %s", trigger.Name)
+		if err := trigger.Handler(ctx, event, match); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *TextRouter) handleError(ctx context.Context, event *events.GuildMessageCreate, err error) {
+	if r.OnError != nil {
+		if err = r.OnError(ctx, event, err); err == nil {
+			return
+		}
+	}
+	e := AsError(err)
+	if e.Kind == ErrorKindInternal {
+		r.Logger.Errorf("Failed to handle text command \"%d\" in %s: %s", event.MessageID, event.ChannelID, err)
+	} else {
+		r.Logger.Debugf("テキストコマンドでエラー: %s", err)
+	}
+	if _, err := event.Client().Rest().CreateMessage(event.ChannelID, discord.MessageCreate{
+		Embeds:           defaultErrorEmbeds(r.Locale, e.Key, e.Data),
+		MessageReference: &discord.MessageReference{MessageID: &event.MessageID},
+	}); err != nil {
+		r.Logger.Errorf("Failed to reply text command error: %s", err)
+	}
+}
+
+// コマンドの一覧を返す
+func (r *TextRouter) Help(prefix string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var b strings.Builder
+	for _, c := range r.list {
+		if c.Hidden {
+			continue
+		}
+		b.WriteString(textCommandHelp(prefix, c))
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func textCommandHelp(prefix string, c *TextCommand) string {
+	help := "`" + prefix + c.Usage() + "`"
+	if c.Description != "" {
+		help += " " + c.Description
+	}
+	if len(c.Aliases) != 0 {
+		help += " (" + strings.Join(c.Aliases, ", ") + ")"
+	}
+	return help
+}
+
+// コマンドの一覧か、指定されたコマンドの使い方を返信するコマンドを返す
+func (r *TextRouter) HelpCommand(name string) TextCommand {
+	return TextCommand{
+		Name:        name,
+		Description: "コマンドの一覧を表示する",
+		Args:        []TextArg{{Name: "command", Optional: true}},
+		Handler: func(ctx context.Context, event *events.GuildMessageCreate, args TextArgs) error {
+			content := r.Help(args.Prefix)
+			if args.Has("command") {
+				c, ok := r.command(args.String("command"))
+				if !ok || c.Hidden {
+					return NotFound("")
+				}
+				content = textCommandHelp(args.Prefix, c)
+			}
+			_, err := event.Client().Rest().CreateMessage(event.ChannelID, discord.MessageCreate{
+				Content:          content,
+				MessageReference: &discord.MessageReference{MessageID: &event.MessageID},
+			})
+			return err
+		},
+	}
+}
+
+type textToken struct {
+	value string
+	// 元の文字列での開始位置
+	start int
+}
+
+// 空白で区切り、ダブルクォートで囲まれた部分はひとつのトークンにする
+// クォートの中では \" と \\ でエスケープできる
+func tokenize(s string) []textToken {
+	var (
+		tokens  []textToken
+		current strings.Builder
+		start   = -1
+		quoted  bool
+		escaped bool
+	)
+	flush := func() {
+		if start >= 0 {
+			tokens = append(tokens, textToken{value: current.String(), start: start})
+		}
+		current.Reset()
+		start = -1
+	}
+	for i, c := range s {
+		switch {
+		case escaped:
+			current.WriteRune(c)
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			if start < 0 {
+				start = i
+			}
+			quoted = !quoted
+		case !quoted && unicode.IsSpace(c):
+			flush()
+		default:
+			if start < 0 {
+				start = i
+			}
+			current.WriteRune(c)
+		}
+	}
+	flush()
+	return tokens
+}
+
+func parseTextArgs(content string, specs []TextArg, tokens []textToken) (TextArgs, error) {
+	args := TextArgs{values: map[string]any{}}
+	for _, t := range tokens {
+		args.Raw = append(args.Raw, t.value)
+	}
+	for i, spec := range specs {
+		if i >= len(tokens) {
+			if !spec.Optional {
+				return args, &OptionError{
+					Option: spec.Name,
+					Key:    OptionErrorRequired,
+					Data:   map[string]any{"Option": spec.Name},
+				}
+			}
+			continue
+		}
+		if spec.Type == TextArgRest {
+			args.values[spec.Name] = strings.TrimSpace(content[tokens[i].start:])
+			break
+		}
+		value, err := parseTextArg(spec.Type, tokens[i].value)
+		if err != nil {
+			return args, &OptionError{
+				Option: spec.Name,
+				Key:    OptionErrorInvalid,
+				Data:   map[string]any{"Option": spec.Name, "Error": err.Error()},
+				Err:    err,
+			}
+		}
+		args.values[spec.Name] = value
+	}
+	return args, nil
+}
+
+func parseTextArg(typ TextArgType, s string) (any, error) {
+	switch typ {
+	case TextArgInt:
+		return strconv.Atoi(s)
+	case TextArgSnowflake:
+		return snowflake.Parse(s)
+	case TextArgUser:
+		return parseMention(s, "<@!", "<@")
+	case TextArgRole:
+		return parseMention(s, "<@&")
+	case TextArgChannel:
+		return parseMention(s, "<#")
+	case TextArgDuration:
+		return ParseDuration(s)
+	default:
+		return s, nil
+	}
+}
+
+// メンションまたはIDからIDを取り出す
+func parseMention(s string, prefixes ...string) (snowflake.ID, error) {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) && strings.HasSuffix(s, ">") {
+			return snowflake.Parse(s[len(prefix) : len(s)-1])
+		}
+	}
+	return snowflake.Parse(s)
+}
+
+var durationUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// 1h30m、2d、1w のような期間を解析する
+// 単位はs、m、h、d、w
+func ParseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, errors.New("empty duration")
+	}
+	var d time.Duration
+	rest := strings.ToLower(s)
+	for rest != "" {
+		i := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+		if i <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		unit, ok := durationUnits[rest[i:i+1]]
+		if !ok {
+			return 0, fmt.Errorf("unknown unit in duration %q", s)
+		}
+		d += time.Duration(n) * unit
+		rest = rest[i+1:]
+	}
+	return d, nil
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/log"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+)
+
+func newTextMessage(guildID snowflake.ID, content string) *events.GuildMessageCreate {
+	return &events.GuildMessageCreate{
+		GenericGuildMessage: &events.GenericGuildMessage{
+			GenericEvent: events.NewGenericEvent(nil, 0, 0),
+			GuildID:      guildID,
+			Message:      discord.Message{Content: content},
+		},
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"  a b  c ", []string{"a", "b", "c"}},
+		{`say "hello world" !`, []string{"say", "hello world", "!"}},
+		{`"a \"quoted\" \\ word"`, []string{`a "quoted" \ word`}},
+		{`"unterminated quote`, []string{"unterminated quote"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, token := range tokenize(tt.in) {
+			got = append(got, token.value)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"90s", 90 * time.Second, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"2D", 48 * time.Hour, false},
+		{"1w1d", 8 * 24 * time.Hour, false},
+		{"", 0, true},
+		{"10", 0, true},
+		{"5y", 0, true},
+		{"h", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %s, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestTextRouter(t *testing.T) {
+	r := NewTextRouter(log.Default(), "!")
+	r.Mention = true
+	r.CaseInsensitive = true
+	r.GuildPrefixes = func(guildID snowflake.ID) []string {
+		if guildID == 1 {
+			return []string{"?", "??"}
+		}
+		return nil
+	}
+
+	var got TextArgs
+	var called string
+	if err := r.AddCommands(TextCommand{
+		Name:    "ban",
+		Aliases: []string{"b"},
+		Args: []TextArg{
+			{Name: "user", Type: TextArgUser},
+			{Name: "for", Type: TextArgDuration, Optional: true},
+			{Name: "reason", Type: TextArgRest, Optional: true},
+		},
+		Handler: func(ctx context.Context, event *events.GuildMessageCreate, args TextArgs) error {
+			called = "ban"
+			got = args
+			return nil
+		},
+	}, r.HelpCommand("help")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddCommands(TextCommand{Name: "B"}); err == nil {
+		t.Error("expected duplicate alias to be rejected")
+	}
+	r.AddTriggers(TextTrigger{
+		Pattern: regexp.MustCompile(`(?i)\bping\b`),
+		Handler: func(ctx context.Context, event *events.GuildMessageCreate, match []string) error {
+			called = "ping"
+			return nil
+		},
+	})
+
+	tests := []struct {
+		guildID snowflake.ID
+		content string
+		called  string
+		prefix  string
+		user    snowflake.ID
+		dur     time.Duration
+		reason  string
+	}{
+		{2, "!ban <@!10> 1d spam  and  more", "ban", "!", 10, 24 * time.Hour, "spam  and  more"},
+		{2, "!B 10", "ban", "!", 10, 0, ""},
+		{2, "<@99> ban <@10>", "ban", "<@99>", 10, 0, ""},
+		{1, "??ban 10 2h", "ban", "??", 10, 2 * time.Hour, ""},
+		{1, "!ban 10", "", "", 0, 0, ""},
+		{2, "!unknown ping", "ping", "", 0, 0, ""},
+		{2, "hello", "", "", 0, 0, ""},
+	}
+	for _, tt := range tests {
+		called, got = "", TextArgs{}
+		if err := r.route(context.Background(), newTextMessage(tt.guildID, tt.content), 99); err != nil {
+			t.Errorf("%q: %s", tt.content, err)
+			continue
+		}
+		if called != tt.called {
+			t.Errorf("%q: called %q, want %q", tt.content, called, tt.called)
+			continue
+		}
+		if called != "ban" {
+			continue
+		}
+		if got.Prefix != tt.prefix || got.Snowflake("user") != tt.user || got.Duration("for") != tt.dur || got.String("reason") != tt.reason {
+			t.Errorf("%q: unexpected args %+v", tt.content, got)
+		}
+	}
+
+	var optionErr *OptionError
+	err := r.route(context.Background(), newTextMessage(2, "!ban"), 99)
+	if !errors.As(err, &optionErr) || optionErr.Key != OptionErrorRequired {
+		t.Errorf("expected required option error, got %v", err)
+	}
+	err = r.route(context.Background(), newTextMessage(2, "!ban <@&10>"), 99)
+	if !errors.As(err, &optionErr) || optionErr.Key != OptionErrorInvalid || AsError(err).Kind != ErrorKindUser {
+		t.Errorf("expected invalid option error, got %v", err)
+	}
+
+	want := "`!ban <user> [for] [reason...]` (b)\n`!help [command]` コマンドの一覧を表示する"
+	if help := r.Help("!"); help != want {
+		t.Errorf("unexpected help:\n%s", help)
+	}
+}
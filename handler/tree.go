@@ -0,0 +1,322 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/disgoorg/json"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+)
+
+var commandNameRegexp = regexp.MustCompile(`^[-_\p{L}\p{N}]{1,32}$`)
+
+// スラッシュコマンドの名前は小文字でなければならない
+// コンテキストメニューの名前は空白や大文字を含められる
+func validCommandName(typ discord.ApplicationCommandType, name string) bool {
+	if typ != discord.ApplicationCommandTypeSlash {
+		n := utf8.RuneCountInString(name)
+		return n >= 1 && n <= 32
+	}
+	return commandNameRegexp.MatchString(name) && strings.IndexFunc(name, unicode.IsUpper) < 0
+}
+
+// サブコマンドとグループを一つの木で宣言するスラッシュコマンド
+// Commandで作成データとハンダラのパスが一致したCommandを生成する
+type CommandTree struct {
+	Name                     string
+	NameLocalizations        map[discord.Locale]string
+	Description              string
+	DescriptionLocalizations map[discord.Locale]string
+	DefaultMemberPermissions *json.Nullable[discord.Permissions]
+	DMPermission             *bool
+	NSFW                     bool
+
+	Check             Check[*events.ApplicationCommandInteractionCreate]
+	AutocompleteCheck Check[*events.AutocompleteInteractionCreate]
+	Middlewares       []Middleware
+	OnError           ErrorHandler
+
+	// サブコマンドがない場合のオプションとハンダラ
+	Options      []discord.ApplicationCommandOption
+	Handler      CommandContextHandler
+	Autocomplete AutocompleteContextHandler
+	Ephemeral    bool
+	Cooldown     *Cooldown
+
+	SubCommands []SubCommand
+	Groups      []SubCommandGroup
+
+	DevOnly bool
+}
+
+type SubCommandGroup struct {
+	Name                     string
+	NameLocalizations        map[discord.Locale]string
+	Description              string
+	DescriptionLocalizations map[discord.Locale]string
+
+	SubCommands []SubCommand
+}
+
+type SubCommand struct {
+	Name                     string
+	NameLocalizations        map[discord.Locale]string
+	Description              string
+	DescriptionLocalizations map[discord.Locale]string
+	Options                  []discord.ApplicationCommandOption
+
+	Check             Check[*events.ApplicationCommandInteractionCreate]
+	AutocompleteCheck Check[*events.AutocompleteInteractionCreate]
+	Handler           CommandContextHandler
+	Autocomplete      AutocompleteContextHandler
+	Ephemeral         bool
+	Cooldown          *Cooldown
+}
+
+func validateName(kind, name, description string) error {
+	if !validCommandName(discord.ApplicationCommandTypeSlash, name) {
+		return fmt.Errorf("invalid %s name %q", kind, name)
+	}
+	if n := len([]rune(description)); n < 1 || n > 100 {
+		return fmt.Errorf("%s %q: description must be 1-100 characters", kind, name)
+	}
+	return nil
+}
+
+// 作成データとハンダラを生成する
+func (t CommandTree) Command() (Command, error) {
+	if err := validateName("command", t.Name, t.Description); err != nil {
+		return Command{}, err
+	}
+	cmd := Command{
+		Check:                       t.Check,
+		AutocompleteCheck:           t.AutocompleteCheck,
+		Checks:                      map[string]Check[*events.ApplicationCommandInteractionCreate]{},
+		AutocompleteChecks:          map[string]Check[*events.AutocompleteInteractionCreate]{},
+		CommandContextHandlers:      map[string]CommandContextHandler{},
+		AutocompleteContextHandlers: map[string]AutocompleteContextHandler{},
+		Ephemeral:                   map[string]bool{},
+		Cooldowns:                   map[string]Cooldown{},
+		Middlewares:                 t.Middlewares,
+		OnError:                     t.OnError,
+		DevOnly:                     t.DevOnly,
+	}
+	create := discord.SlashCommandCreate{
+		Name:                     t.Name,
+		NameLocalizations:        t.NameLocalizations,
+		Description:              t.Description,
+		DescriptionLocalizations: t.DescriptionLocalizations,
+		DefaultMemberPermissions: t.DefaultMemberPermissions,
+		DMPermission:             t.DMPermission,
+		NSFW:                     t.NSFW,
+	}
+
+	if len(t.SubCommands) == 0 && len(t.Groups) == 0 {
+		if t.Handler == nil {
+			return Command{}, fmt.Errorf("command %q has no handler", t.Name)
+		}
+		create.Options = t.Options
+		cmd.CommandContextHandlers[""] = t.Handler
+		cmd.Ephemeral[""] = t.Ephemeral
+		if t.Cooldown != nil {
+			cmd.Cooldowns[""] = *t.Cooldown
+		}
+		if t.Autocomplete != nil {
+			cmd.AutocompleteContextHandlers[""] = t.Autocomplete
+		}
+		cmd.Create = create
+		return cmd, nil
+	}
+	if t.Handler != nil || len(t.Options) != 0 {
+		return Command{}, fmt.Errorf("command %q cannot have both options and subcommands", t.Name)
+	}
+	if t.Cooldown != nil {
+		return Command{}, fmt.Errorf("command %q: cooldown must be set on each subcommand", t.Name)
+	}
+
+	names := map[string]struct{}{}
+	addName := func(name string) error {
+		if _, ok := names[name]; ok {
+			return fmt.Errorf("command %q: duplicate name %q", t.Name, name)
+		}
+		names[name] = struct{}{}
+		return nil
+	}
+	for _, group := range t.Groups {
+		if err := validateName("subcommand group", group.Name, group.Description); err != nil {
+			return Command{}, err
+		}
+		if err := addName(group.Name); err != nil {
+			return Command{}, err
+		}
+		if len(group.SubCommands) == 0 {
+			return Command{}, fmt.Errorf("subcommand group %q has no subcommands", group.Name)
+		}
+		option := discord.ApplicationCommandOptionSubCommandGroup{
+			Name:                     group.Name,
+			NameLocalizations:        group.NameLocalizations,
+			Description:              group.Description,
+			DescriptionLocalizations: group.DescriptionLocalizations,
+		}
+		groupNames := map[string]struct{}{}
+		for _, sub := range group.SubCommands {
+			if _, ok := groupNames[sub.Name]; ok {
+				return Command{}, fmt.Errorf("subcommand group %q: duplicate name %q", group.Name, sub.Name)
+			}
+			groupNames[sub.Name] = struct{}{}
+			subOption, err := sub.register(&cmd, buildCommandPath(&sub.Name, &group.Name))
+			if err != nil {
+				return Command{}, err
+			}
+			option.Options = append(option.Options, subOption)
+		}
+		create.Options = append(create.Options, option)
+	}
+	for _, sub := range t.SubCommands {
+		if err := addName(sub.Name); err != nil {
+			return Command{}, err
+		}
+		subOption, err := sub.register(&cmd, sub.Name)
+		if err != nil {
+			return Command{}, err
+		}
+		create.Options = append(create.Options, subOption)
+	}
+	cmd.Create = create
+	return cmd, nil
+}
+
+func (s SubCommand) register(cmd *Command, path string) (discord.ApplicationCommandOptionSubCommand, error) {
+	if err := validateName("subcommand", s.Name, s.Description); err != nil {
+		return discord.ApplicationCommandOptionSubCommand{}, err
+	}
+	if s.Handler == nil {
+		return discord.ApplicationCommandOptionSubCommand{}, fmt.Errorf("subcommand %q has no handler", path)
+	}
+	cmd.CommandContextHandlers[path] = s.Handler
+	cmd.Ephemeral[path] = s.Ephemeral
+	if s.Cooldown != nil {
+		cmd.Cooldowns[path] = *s.Cooldown
+	}
+	if s.Check != nil {
+		cmd.Checks[path] = s.Check
+	}
+	if s.AutocompleteCheck != nil {
+		cmd.AutocompleteChecks[path] = s.AutocompleteCheck
+	}
+	if s.Autocomplete != nil {
+		cmd.AutocompleteContextHandlers[path] = s.Autocomplete
+	}
+	return discord.ApplicationCommandOptionSubCommand{
+		Name:                     s.Name,
+		NameLocalizations:        s.NameLocalizations,
+		Description:              s.Description,
+		DescriptionLocalizations: s.DescriptionLocalizations,
+		Options:                  s.Options,
+	}, nil
+}
+
+// コマンドの作成データから呼び出されうるパスと、オートコンプリートを持つかを返す
+func commandPaths(create discord.ApplicationCommandCreate) map[string]bool {
+	c, ok := create.(discord.SlashCommandCreate)
+	if !ok {
+		return map[string]bool{"": false}
+	}
+	paths := map[string]bool{}
+	var hasSub bool
+	for _, option := range c.Options {
+		switch o := option.(type) {
+		case discord.ApplicationCommandOptionSubCommand:
+			hasSub = true
+			paths[o.Name] = hasAutocomplete(o.Options)
+		case discord.ApplicationCommandOptionSubCommandGroup:
+			hasSub = true
+			for _, sub := range o.Options {
+				paths[buildCommandPath(&sub.Name, &o.Name)] = hasAutocomplete(sub.Options)
+			}
+		}
+	}
+	if !hasSub {
+		paths[""] = hasAutocomplete(c.Options)
+	}
+	return paths
+}
+
+func hasAutocomplete(options []discord.ApplicationCommandOption) bool {
+	for _, option := range options {
+		switch o := option.(type) {
+		case discord.ApplicationCommandOptionString:
+			if o.Autocomplete {
+				return true
+			}
+		case discord.ApplicationCommandOptionInt:
+			if o.Autocomplete {
+				return true
+			}
+		case discord.ApplicationCommandOptionFloat:
+			if o.Autocomplete {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// 登録されたコマンドの作成データとハンダラのパスが一致しているか検証する
+func (h *Handler) Validate() error {
+	var errs []error
+	commands := h.commands()
+	sort.Slice(commands, func(i, j int) bool {
+		a, b := commands[i].Create, commands[j].Create
+		if a.CommandName() != b.CommandName() {
+			return a.CommandName() < b.CommandName()
+		}
+		return a.Type() < b.Type()
+	})
+	for _, cmd := range commands {
+		name := cmd.Create.CommandName()
+		if !validCommandName(cmd.Create.Type(), name) {
+			errs = append(errs, fmt.Errorf("invalid command name %q", name))
+		}
+		paths := commandPaths(cmd.Create)
+		for _, path := range sortedKeys(paths) {
+			if _, ok := cmd.commandHandler(path); !ok {
+				errs = append(errs, fmt.Errorf("command %q: no handler for path %q", name, path))
+			}
+			if _, ok := cmd.autocompleteHandler(path); paths[path] && !ok {
+				errs = append(errs, fmt.Errorf("command %q: no autocomplete handler for path %q", name, path))
+			}
+		}
+		check := func(kind string, keys []string) {
+			for _, path := range keys {
+				if _, ok := paths[path]; !ok {
+					errs = append(errs, fmt.Errorf("command %q: %s for unknown path %q", name, kind, path))
+				}
+			}
+		}
+		check("handler", sortedKeys(cmd.CommandHandlers))
+		check("handler", sortedKeys(cmd.CommandContextHandlers))
+		check("autocomplete handler", sortedKeys(cmd.AutocompleteHandlers))
+		check("autocomplete handler", sortedKeys(cmd.AutocompleteContextHandlers))
+		check("check", sortedKeys(cmd.Checks))
+		check("autocomplete check", sortedKeys(cmd.AutocompleteChecks))
+		check("ephemeral flag", sortedKeys(cmd.Ephemeral))
+		check("cooldown", sortedKeys(cmd.Cooldowns))
+	}
+	return errors.Join(errs...)
+}
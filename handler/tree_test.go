@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/disgoorg/log"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+)
+
+func noopCommand(context.Context, *events.ApplicationCommandInteractionCreate) error {
+	return nil
+}
+
+func TestCommandTree(t *testing.T) {
+	cmd, err := CommandTree{
+		Name:        "role",
+		Description: "role command",
+		SubCommands: []SubCommand{
+			{Name: "list", Description: "list roles", Handler: noopCommand, Ephemeral: true},
+		},
+		Groups: []SubCommandGroup{
+			{
+				Name:        "config",
+				Description: "config",
+				SubCommands: []SubCommand{
+					{Name: "set", Description: "set config", Handler: noopCommand},
+				},
+			},
+		},
+	}.Command()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"list", "config/set"} {
+		if _, ok := cmd.commandHandler(path); !ok {
+			t.Errorf("no handler for %q", path)
+		}
+	}
+	if !cmd.Ephemeral["list"] {
+		t.Error("expected list to be ephemeral")
+	}
+
+	h := New(log.Default())
+	h.AddCommands(cmd)
+	if err := h.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %s", err)
+	}
+}
+
+func TestCommandTreeInvalid(t *testing.T) {
+	tests := map[string]CommandTree{
+		"no handler": {Name: "a", Description: "a"},
+		"invalid name": {
+			Name: "A B", Description: "a", Handler: noopCommand,
+		},
+		"uppercase name": {
+			Name: "Role", Description: "a", Handler: noopCommand,
+		},
+		"uppercase subcommand": {
+			Name: "a", Description: "a",
+			SubCommands: []SubCommand{{Name: "List", Description: "b", Handler: noopCommand}},
+		},
+		"duplicate subcommand": {
+			Name: "a", Description: "a",
+			SubCommands: []SubCommand{
+				{Name: "b", Description: "b", Handler: noopCommand},
+				{Name: "b", Description: "b", Handler: noopCommand},
+			},
+		},
+		"options and subcommands": {
+			Name: "a", Description: "a", Handler: noopCommand,
+			SubCommands: []SubCommand{{Name: "b", Description: "b", Handler: noopCommand}},
+		},
+	}
+	for name, tree := range tests {
+		if _, err := tree.Command(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestValidateMismatch(t *testing.T) {
+	h := New(log.Default())
+	h.AddCommands(Command{
+		Create: discord.SlashCommandCreate{
+			Name: "role",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionSubCommand{Name: "add"},
+				discord.ApplicationCommandOptionSubCommand{
+					Name: "remove",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionString{Name: "role", Autocomplete: true},
+					},
+				},
+			},
+		},
+		CommandHandlers: map[string]CommandHandler{
+			"add":    func(*events.ApplicationCommandInteractionCreate) error { return nil },
+			"remvoe": func(*events.ApplicationCommandInteractionCreate) error { return nil },
+		},
+	})
+	err := h.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{
+		`no handler for path "remove"`,
+		`no autocomplete handler for path "remove"`,
+		`handler for unknown path "remvoe"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %q", want, err)
+		}
+	}
+}
+
+func TestValidateCommandNames(t *testing.T) {
+	h := New(log.Default())
+	h.AddCommands(
+		UserCommand{Name: "Show Info"}.Command(),
+		MessageCommand{Name: "Report Message"}.Command(),
+	)
+	if err := h.Validate(); err != nil {
+		t.Errorf("expected context menu names to be valid: %s", err)
+	}
+
+	h.AddCommands(Command{
+		Create:                 discord.SlashCommandCreate{Name: "Info"},
+		CommandContextHandlers: map[string]CommandContextHandler{"": noopCommand},
+	})
+	if err := h.Validate(); err == nil || !strings.Contains(err.Error(), `invalid command name "Info"`) {
+		t.Errorf("expected uppercase slash command to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,366 @@
+package handler
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/google/uuid"
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+	"github.com/sabafly/sabafly-disgo/rest"
+)
+
+// 受け取ったイベントをハンダラより先に渡す待機
+// deliverはイベントを受け取ったゴルーチンで呼ばれるので、すぐに返さなければならない
+// deliverはイベントを受け取った場合にtrueを返す
+type waiter struct {
+	check   func(event bot.Event) bool
+	deliver func(event bot.Event) bool
+}
+
+func (h *Handler) addWaiter(id uuid.UUID, w waiter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.waiters[id] = w
+}
+
+func (h *Handler) removeWaiter(id uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.waiters, id)
+}
+
+// イベントを待っているハンダラに渡し、いずれかが受け取った場合はtrueを返す
+func (h *Handler) notifyWaiters(event bot.Event) bool {
+	h.mu.RLock()
+	if len(h.waiters) == 0 {
+		h.mu.RUnlock()
+		return false
+	}
+	waiters := make([]waiter, 0, len(h.waiters))
+	for _, w := range h.waiters {
+		waiters = append(waiters, w)
+	}
+	h.mu.RUnlock()
+	var taken bool
+	for _, w := range waiters {
+		h.invoke("waiter", event, func() error {
+			if w.check(event) && w.deliver(event) {
+				taken = true
+			}
+			return nil
+		})
+	}
+	return taken
+}
+
+// 一度しか応答できないインタラクションのイベント
+func isInteractionEvent(event bot.Event) bool {
+	switch event.(type) {
+	case *events.ApplicationCommandInteractionCreate, *events.AutocompleteInteractionCreate,
+		*events.ComponentInteractionCreate, *events.ModalSubmitInteractionCreate:
+		return true
+	}
+	return false
+}
+
+// 条件に一致するイベントを一度だけ待つ
+// 一致したイベントを送るか、ctxが終了した時点で待機を解除してチャンネルを閉じる
+// 待ち始めた後に受け取ったイベントだけが対象になる
+// 受け取ったインタラクションはハンダラには渡されないので、呼び出し側が応答する
+//
+// 待っているイベントはワーカーのキューに入る前に渡されるため、StartDispatcherで同じチャンネルのイベントを待っても詰まらない
+// イベントを同期的に処理している場合はハンダラの中で待つと次のイベントを受け取れないため、ASyncかStartDispatcherと併用すること
+func WaitForChan[T bot.Event](ctx context.Context, h *Handler, check func(event T) bool) <-chan T {
+	id := uuid.New()
+	ch := make(chan T, 1)
+	done := make(chan struct{})
+	var once sync.Once
+	finish := func(event T, ok bool) (finished bool) {
+		once.Do(func() {
+			finished = true
+			h.removeWaiter(id)
+			if ok {
+				ch <- event
+			}
+			close(ch)
+			close(done)
+		})
+		return finished
+	}
+	h.addWaiter(id, waiter{
+		check: func(event bot.Event) bool {
+			e, ok := event.(T)
+			return ok && (check == nil || check(e))
+		},
+		deliver: func(event bot.Event) bool {
+			return finish(event.(T), true)
+		},
+	})
+	go func() {
+		select {
+		case <-ctx.Done():
+			var zero T
+			finish(zero, false)
+		case <-done:
+		}
+	}()
+	return ch
+}
+
+// 条件に一致するイベントを一度だけ待つ
+// ctxが先に終了した場合はctxのエラーを返す
+func WaitFor[T bot.Event](ctx context.Context, h *Handler, check func(event T) bool) (T, error) {
+	event, ok := <-WaitForChan(ctx, h, check)
+	if !ok {
+		return event, ctx.Err()
+	}
+	return event, nil
+}
+
+// モーダルで応答できるインタラクション
+type ModalResponder interface {
+	CreateModal(discord.ModalCreate, ...rest.RequestOpt) error
+}
+
+// 対話の手順
+type ConversationStep func(c *Conversation) error
+
+// 一人のユーザーとの複数の手順からなる対話
+// 送信したプロンプトや待っているハンダラはCloseでまとめて後片付けされる
+type Conversation struct {
+	h      *Handler
+	client bot.Client
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	ChannelID snowflake.ID
+	UserID    snowflake.ID
+	// 各手順で待つ時間
+	// 0の場合は対話が終了するまで待つ
+	Timeout time.Duration
+	// Closeで送信したプロンプトを削除する
+	DeletePrompts bool
+
+	mu       sync.Mutex
+	cleanups []func()
+	closed   bool
+}
+
+func (h *Handler) NewConversation(ctx context.Context, client bot.Client, channelID, userID snowflake.ID) *Conversation {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Conversation{
+		h:         h,
+		client:    client,
+		ctx:       ctx,
+		cancel:    cancel,
+		ChannelID: channelID,
+		UserID:    userID,
+	}
+}
+
+// 対話が終了するとキャンセルされるコンテキスト
+func (c *Conversation) Context() context.Context {
+	return c.ctx
+}
+
+// Closeで実行する後片付けを追加する
+// 後に追加したものから実行される
+func (c *Conversation) OnClose(f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		f()
+		return
+	}
+	c.cleanups = append(c.cleanups, f)
+}
+
+// 待っているハンダラを削除し、後片付けを実行する
+func (c *Conversation) Close() {
+	c.cancel()
+	c.mu.Lock()
+	cleanups := c.cleanups
+	c.cleanups = nil
+	c.closed = true
+	c.mu.Unlock()
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+}
+
+// 手順を順に実行し、最後にCloseする
+// エラーを返した手順があればそこで終了する
+func (c *Conversation) Run(steps ...ConversationStep) error {
+	defer c.Close()
+	for _, step := range steps {
+		if err := step(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Conversation) stepContext() (context.Context, context.CancelFunc) {
+	if c.Timeout > 0 {
+		return context.WithTimeout(c.ctx, c.Timeout)
+	}
+	return context.WithCancel(c.ctx)
+}
+
+func waitStep[T bot.Event](c *Conversation, check func(event T) bool) (T, error) {
+	ctx, cancel := c.stepContext()
+	defer cancel()
+	return WaitFor(ctx, c.h, check)
+}
+
+// 対話の相手が対話のチャンネルに送る次のメッセージを待つ
+func (c *Conversation) NextMessage() (discord.Message, error) {
+	event, err := waitStep(c, c.isReply)
+	if err != nil {
+		return discord.Message{}, err
+	}
+	message, _ := conversationMessage(event)
+	return message, nil
+}
+
+func (c *Conversation) isReply(event bot.Event) bool {
+	message, ok := conversationMessage(event)
+	return ok && message.ChannelID == c.ChannelID && message.Author.ID == c.UserID
+}
+
+func conversationMessage(event bot.Event) (discord.Message, bool) {
+	switch e := event.(type) {
+	case *events.GuildMessageCreate:
+		return e.Message, true
+	case *events.DMMessageCreate:
+		return e.Message, true
+	}
+	return discord.Message{}, false
+}
+
+// 対話の相手がメッセージに付ける次のリアクションを待つ
+func (c *Conversation) NextReaction(messageID snowflake.ID) (*events.GuildMessageReactionAdd, error) {
+	return waitStep(c, func(event *events.GuildMessageReactionAdd) bool {
+		return event.MessageID == messageID && event.UserID == c.UserID
+	})
+}
+
+// 対話の相手がメッセージのコンポーネントを操作するのを待つ
+// 受け取ったインタラクションには呼び出し側が応答する
+func (c *Conversation) NextComponent(messageID snowflake.ID) (*events.ComponentInteractionCreate, error) {
+	return waitStep(c, func(event *events.ComponentInteractionCreate) bool {
+		return event.Message.ID == messageID && event.User().ID == c.UserID
+	})
+}
+
+// 対話の相手がモーダルを送信するのを待つ
+// 受け取ったインタラクションには呼び出し側が応答する
+func (c *Conversation) NextModal(customID string) (*events.ModalSubmitInteractionCreate, error) {
+	return waitStep(c, func(event *events.ModalSubmitInteractionCreate) bool {
+		return event.Data.CustomID == customID && event.User().ID == c.UserID
+	})
+}
+
+// 対話のチャンネルにメッセージを送る
+// DeletePromptsがtrueの場合はCloseで削除する
+func (c *Conversation) Prompt(message discord.MessageCreate) (*discord.Message, error) {
+	m, err := c.client.Rest().CreateMessage(c.ChannelID, message, rest.WithCtx(c.ctx))
+	if err != nil {
+		return nil, err
+	}
+	if c.DeletePrompts {
+		c.OnClose(func() {
+			if err := c.client.Rest().DeleteMessage(m.ChannelID, m.ID); err != nil {
+				c.h.Logger.Debugf("プロンプトの削除に失敗 %d: %s", m.ID, err)
+			}
+		})
+	}
+	return m, nil
+}
+
+// メッセージを送り、対話の相手の返信を待つ
+func (c *Conversation) Ask(message discord.MessageCreate) (discord.Message, error) {
+	ctx, cancel := c.stepContext()
+	defer cancel()
+	// 送信直後の返信を逃さないように送る前に待ち始める
+	ch := WaitForChan(ctx, c.h, c.isReply)
+	if _, err := c.Prompt(message); err != nil {
+		return discord.Message{}, err
+	}
+	event, ok := <-ch
+	if !ok {
+		return discord.Message{}, ctx.Err()
+	}
+	reply, _ := conversationMessage(event)
+	return reply, nil
+}
+
+// コンポーネント付きのメッセージを送り、対話の相手の操作を待つ
+func (c *Conversation) Select(message discord.MessageCreate) (*events.ComponentInteractionCreate, error) {
+	ctx, cancel := c.stepContext()
+	defer cancel()
+	// 送信直後の操作を逃さないように送る前に待ち始め、メッセージIDが分かるまではカスタムIDで照合する
+	customIDs := componentCustomIDs(message.Components)
+	var (
+		mu        sync.Mutex
+		messageID snowflake.ID
+	)
+	ch := WaitForChan(ctx, c.h, func(event *events.ComponentInteractionCreate) bool {
+		if event.User().ID != c.UserID {
+			return false
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if messageID != 0 {
+			return event.Message.ID == messageID
+		}
+		return event.Message.ChannelID == c.ChannelID && slices.Contains(customIDs, event.Data.CustomID())
+	})
+	m, err := c.Prompt(message)
+	if err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	messageID = m.ID
+	mu.Unlock()
+	event, ok := <-ch
+	if !ok {
+		return nil, ctx.Err()
+	}
+	return event, nil
+}
+
+func componentCustomIDs(components []discord.ContainerComponent) []string {
+	var customIDs []string
+	for _, container := range components {
+		for _, component := range container.Components() {
+			if id := component.ID(); id != "" {
+				customIDs = append(customIDs, id)
+			}
+		}
+	}
+	return customIDs
+}
+
+// モーダルで応答し、対話の相手の送信を待つ
+func (c *Conversation) AskModal(interaction ModalResponder, modal discord.ModalCreate) (*events.ModalSubmitInteractionCreate, error) {
+	ctx, cancel := c.stepContext()
+	defer cancel()
+	// モーダルを開く前に待ち始める
+	ch := WaitForChan(ctx, c.h, func(event *events.ModalSubmitInteractionCreate) bool {
+		return event.Data.CustomID == modal.CustomID && event.User().ID == c.UserID
+	})
+	if err := interaction.CreateModal(modal, rest.WithCtx(c.ctx)); err != nil {
+		return nil, err
+	}
+	event, ok := <-ch
+	if !ok {
+		return nil, ctx.Err()
+	}
+	return event, nil
+}
@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/log"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sabafly/sabafly-disgo/bot"
+	"github.com/sabafly/sabafly-disgo/discord"
+	"github.com/sabafly/sabafly-disgo/events"
+	"github.com/sabafly/sabafly-disgo/rest"
+)
+
+type testConversationClient struct {
+	bot.Client
+	rest *testConversationRest
+}
+
+func (c *testConversationClient) Rest() rest.Rest { return c.rest }
+
+// メッセージの送信が返る前にsentを呼ぶ
+type testConversationRest struct {
+	rest.Rest
+	sent func()
+}
+
+func (r *testConversationRest) CreateMessage(channelID snowflake.ID, _ discord.MessageCreate, _ ...rest.RequestOpt) (*discord.Message, error) {
+	r.sent()
+	return &discord.Message{ID: 10, ChannelID: channelID}, nil
+}
+
+func TestWaitFor(t *testing.T) {
+	h := New(log.Default())
+	ch := WaitForChan(context.Background(), h, func(event *events.GuildMessageCreate) bool {
+		return event.ChannelID == 2
+	})
+	h.OnEvent(newDMMessageCreate(2, 1))
+	h.OnEvent(newGuildMessageCreate(1))
+	h.OnEvent(newGuildMessageCreate(2))
+	h.OnEvent(newGuildMessageCreate(2))
+	event, ok := <-ch
+	if !ok || event.ChannelID != 2 {
+		t.Fatalf("unexpected event %v %v", event, ok)
+	}
+	if _, ok := <-ch; ok {
+		t.Error("expected channel to be closed after the first match")
+	}
+	if len(h.waiters) != 0 {
+		t.Errorf("expected waiter to be removed, got %d", len(h.waiters))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := WaitFor[*events.GuildMessageCreate](ctx, h, nil); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if len(h.waiters) != 0 {
+		t.Errorf("expected waiter to be removed after timeout, got %d", len(h.waiters))
+	}
+}
+
+func TestConversation(t *testing.T) {
+	h := New(log.Default())
+	c := h.NewConversation(context.Background(), nil, 1, 2)
+	c.Timeout = time.Second
+	var cleaned bool
+	c.OnClose(func() { cleaned = true })
+
+	var answers []string
+	result := make(chan error, 1)
+	go func() {
+		result <- c.Run(
+			func(c *Conversation) error {
+				m, err := c.NextMessage()
+				answers = append(answers, m.Content)
+				return err
+			},
+			func(c *Conversation) error {
+				m, err := c.NextMessage()
+				answers = append(answers, m.Content)
+				return err
+			},
+		)
+	}()
+
+	for i := 0; ; i++ {
+		select {
+		case err := <-result:
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(answers) != 2 || answers[0] != "answer" || answers[1] != "answer" {
+				t.Errorf("unexpected answers %q", answers)
+			}
+			if !cleaned {
+				t.Error("expected cleanup to run")
+			}
+			if c.Context().Err() == nil {
+				t.Error("expected conversation context to be canceled")
+			}
+			return
+		default:
+		}
+		other := newDMMessageCreate(1, 3)
+		other.Message.Content = "other"
+		h.OnEvent(other)
+		answer := newDMMessageCreate(1, 2)
+		answer.Message.Content = "answer"
+		h.OnEvent(answer)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// ワーカープールで処理中のハンダラから同じチャンネルの次のメッセージを待つ
+func TestConversationInDispatcher(t *testing.T) {
+	h := New(log.Default())
+	h.StartDispatcher(DispatcherConfig{Workers: 1})
+	defer h.Shutdown(context.Background())
+	result := make(chan string, 1)
+	h.AddDMMessage(DMMessage{
+		Handler: func(event *events.DMMessageCreate) error {
+			if event.Message.Content != "start" {
+				return nil
+			}
+			c := h.NewConversation(context.Background(), nil, event.ChannelID, event.Message.Author.ID)
+			c.Timeout = time.Second
+			defer c.Close()
+			m, err := c.NextMessage()
+			if err != nil {
+				result <- err.Error()
+				return nil
+			}
+			result <- m.Content
+			return nil
+		},
+	})
+	start := newDMMessageCreate(1, 2)
+	start.Message.Content = "start"
+	h.OnEvent(start)
+	waitWaiters(t, h, 1)
+	answer := newDMMessageCreate(1, 2)
+	answer.Message.Content = "answer"
+	h.OnEvent(answer)
+	if got := <-result; got != "answer" {
+		t.Errorf("expected the answer, got %q", got)
+	}
+}
+
+// 送信が返る前に届いた返信も受け取る
+func TestConversationAskFastReply(t *testing.T) {
+	h := New(log.Default())
+	client := &testConversationClient{rest: &testConversationRest{sent: func() {
+		answer := newDMMessageCreate(1, 2)
+		answer.Message.Content = "answer"
+		h.OnEvent(answer)
+	}}}
+	c := h.NewConversation(context.Background(), client, 1, 2)
+	c.Timeout = 100 * time.Millisecond
+	defer c.Close()
+	m, err := c.Ask(discord.MessageCreate{Content: "question"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Content != "answer" {
+		t.Errorf("expected the answer, got %q", m.Content)
+	}
+	if len(h.waiters) != 0 {
+		t.Errorf("expected waiter to be removed, got %d", len(h.waiters))
+	}
+}
+
+// 待っている側が受け取ったインタラクションはハンダラに渡さず、メッセージは渡す
+func TestWaitForTakesInteraction(t *testing.T) {
+	h := New(log.Default())
+	var commands, messages int
+	h.AddCommands(Command{
+		Create: discord.SlashCommandCreate{},
+		CommandContextHandlers: map[string]CommandContextHandler{
+			"": func(context.Context, *events.ApplicationCommandInteractionCreate) error {
+				commands++
+				return nil
+			},
+		},
+	})
+	h.AddMessages(Message{
+		Handler: func(*events.GuildMessageCreate) error {
+			messages++
+			return nil
+		},
+	})
+	newCommand := func() *events.ApplicationCommandInteractionCreate {
+		return &events.ApplicationCommandInteractionCreate{
+			GenericEvent:                  events.NewGenericEvent(nil, 0, 0),
+			ApplicationCommandInteraction: discord.ApplicationCommandInteraction{Data: discord.SlashCommandInteractionData{}},
+			Respond: func(discord.InteractionResponseType, discord.InteractionResponseData, ...rest.RequestOpt) error {
+				return nil
+			},
+		}
+	}
+
+	command := WaitForChan[*events.ApplicationCommandInteractionCreate](context.Background(), h, nil)
+	message := WaitForChan[*events.GuildMessageCreate](context.Background(), h, nil)
+	h.OnEvent(newCommand())
+	h.OnEvent(newGuildMessageCreate(1))
+	if _, ok := <-command; !ok {
+		t.Fatal("expected the command to be delivered")
+	}
+	if _, ok := <-message; !ok {
+		t.Fatal("expected the message to be delivered")
+	}
+	if commands != 0 {
+		t.Errorf("expected the awaited command not to be dispatched, got %d calls", commands)
+	}
+	if messages != 1 {
+		t.Errorf("expected the awaited message to be dispatched, got %d calls", messages)
+	}
+
+	h.OnEvent(newCommand())
+	if commands != 1 {
+		t.Errorf("expected the next command to be dispatched, got %d calls", commands)
+	}
+}